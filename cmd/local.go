@@ -2,20 +2,26 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/terraform-tools/terraform-checker/pkg/config"
 	"github.com/terraform-tools/terraform-checker/pkg/filter"
 	"github.com/terraform-tools/terraform-checker/pkg/local"
 	"github.com/terraform-tools/terraform-checker/pkg/terraform"
 )
 
 var (
-	fmtCheck      bool //nolint:gochecknoglobals // don't think there's another way
-	validateCheck bool //nolint:gochecknoglobals // don't think there's another way
-	tfLintCheck   bool //nolint:gochecknoglobals // don't think there's another way
+	pluginChecks []string      //nolint:gochecknoglobals // don't think there's another way
+	localOpts    local.Options //nolint:gochecknoglobals // don't think there's another way
 )
 
 func LocalCmd() *cobra.Command {
+	registry := terraform.DefaultRegistry()
+	// One flag per registered check
+	enabledChecks := make(map[string]*bool)
+
 	localCmd := &cobra.Command{
 		Use:   "local",
 		Short: "run terraform-checker in local mode",
@@ -23,29 +29,39 @@ func LocalCmd() *cobra.Command {
 			if len(args) != 1 {
 				return fmt.Errorf("you must provide a path as first and only arg")
 			}
-			return nil
+			if info, err := os.Stat(args[0]); err != nil || !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", args[0])
+			}
+			return localOpts.Validate()
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			tfChecksTypes := []string{}
-			if fmtCheck {
-				tfChecksTypes = append(tfChecksTypes, terraform.Fmt.String())
-			}
-			if validateCheck {
-				tfChecksTypes = append(tfChecksTypes, terraform.Validate.String())
-			}
-			if tfLintCheck {
-				tfChecksTypes = append(tfChecksTypes, terraform.TFLint.String())
+			for _, name := range registry.Names() {
+				if *enabledChecks[name] {
+					tfChecksTypes = append(tfChecksTypes, name)
+				}
 			}
 
-			if len(tfChecksTypes) == 0 {
-				tfChecksTypes = terraform.AllTfCheckTypes()
-			}
+			tfChecksTypes = append(tfChecksTypes, pluginChecks...)
 
-			local.StartLocal(args[0], parallelism, filter.TfCheckTypeFilter{TfCheckTypes: tfChecksTypes})
+			localOpts.Parallelism = parallelism
+			os.Exit(local.StartLocal(args[0], filter.TfCheckTypeFilter{TfCheckTypes: tfChecksTypes}, localOpts))
 		},
 	}
-	localCmd.PersistentFlags().BoolVarP(&fmtCheck, "fmt", "", false, "Whether to execute fmt check or not")
-	localCmd.PersistentFlags().BoolVarP(&validateCheck, "validate", "", false, "Whether to execute validate check or not")
-	localCmd.PersistentFlags().BoolVarP(&tfLintCheck, "tflint", "", false, "Whether to execute tflint check or not")
+	for _, def := range registry.Definitions() {
+		enabledChecks[def.Name] = localCmd.PersistentFlags().Bool(def.Name, false, fmt.Sprintf("Whether to execute %s check or not", def.Description))
+	}
+	localCmd.PersistentFlags().StringSliceVarP(&pluginChecks, "plugin", "", []string{}, "Plugins declared in .tf-checker files to execute")
+	localCmd.PersistentFlags().BoolVarP(&localOpts.AllowRepoPlugins, "allow-repo-plugins", "", false, "Load the plugins declared in .tf-checker files, they run commands of the checked repository and must be named with --plugin")
+	localCmd.PersistentFlags().StringVarP(&localOpts.Output.Format, "format", "f", local.FormatText, fmt.Sprintf("Output format, one of %s", strings.Join(local.Formats(), ", ")))
+	localCmd.PersistentFlags().StringVarP(&localOpts.Output.File, "output-file", "o", "", "File to write the output to instead of stdout")
+	localCmd.PersistentFlags().StringVarP(&localOpts.FailOn, "fail-on", "", local.SeverityNotice, fmt.Sprintf("Minimum severity of the failing checks making the command fail, one of %s", strings.Join(local.Severities(), ", ")))
+	localCmd.PersistentFlags().BoolVarP(&localOpts.Fix, "fix", "", false, "Apply the available fixes of the failing checks in place, then run the checks again")
+	localCmd.PersistentFlags().StringVarP(&localOpts.ProviderInstallation.PluginCacheDir, "plugin-cache-dir", "", "", "Terraform plugin cache dir shared by the checks")
+	localCmd.PersistentFlags().StringVarP(&localOpts.ProviderInstallation.FilesystemMirror, "filesystem-mirror", "", "", "Dir of a filesystem mirror to install the terraform providers from")
+	localCmd.PersistentFlags().StringVarP(&localOpts.ProviderInstallation.NetworkMirror, "network-mirror", "", "", "URL of a network mirror to install the terraform providers from")
+	localCmd.PersistentFlags().DurationVarP(&localOpts.CheckTimeout, "check-timeout", "", config.DefaultCheckTimeout, "Timeout of each check, unless set by the check_timeout of the .tf-checker file of the terraform dir")
+	localCmd.PersistentFlags().DurationVarP(&localOpts.Timeout, "timeout", "", 0, "Timeout of the whole run, 0 for none")
+	localCmd.PersistentFlags().StringVarP(&localOpts.TerraformVersionsDir, "terraform-versions-dir", "", "", "Dir of the installed terraform versions, as <dir>/<version>/terraform, to pick the one of each terraform dir from")
 	return localCmd
 }
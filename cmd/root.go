@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/terraform-tools/terraform-checker/pkg/local"
 )
 
 const DefaultParallelism = 10
@@ -29,7 +30,7 @@ func Execute() {
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(local.ExitError)
 	}
 }
 
@@ -1,16 +1,48 @@
 package cmd
 
 import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"github.com/terraform-tools/terraform-checker/pkg/config"
 	"github.com/terraform-tools/terraform-checker/pkg/server"
 )
 
+var serverOverrides = config.Overrides{} //nolint:gochecknoglobals // don't think there's another way
+
+// overrideFlag is a flag setting the override of a config field.
+type overrideFlag struct {
+	path string
+}
+
+func (f overrideFlag) String() string {
+	return serverOverrides[f.path]
+}
+
+func (f overrideFlag) Set(value string) error {
+	serverOverrides[f.path] = value
+	return nil
+}
+
+func (f overrideFlag) Type() string {
+	return "string"
+}
+
 func ServerCmd() *cobra.Command {
-	return &cobra.Command{
+	serverCmd := &cobra.Command{
 		Use:   "server",
 		Short: "run terraform-checker in server mode",
 		Run: func(cmd *cobra.Command, args []string) {
-			server.StartServer()
+			if err := server.StartServer(serverOverrides); err != nil {
+				log.Fatal().Err(err).Msg("Error running server")
+			}
 		},
 	}
+	// Every field of the config can be overridden, by a flag or by its env var
+	for _, path := range config.Fields() {
+		serverCmd.PersistentFlags().Var(overrideFlag{path: path}, config.FlagName(path),
+			fmt.Sprintf("Overrides %s of the config, also set by %s", path, config.EnvName(path)))
+	}
+	return serverCmd
 }
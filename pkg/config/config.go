@@ -1,43 +1,128 @@
 package config
 
 import (
+	stderrors "errors"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
-	"github.com/rs/zerolog/log"
 	"github.com/terraform-tools/terraform-checker/pkg/errors"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
 
 	"github.com/palantir/go-githubapp/githubapp"
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
-	GithubHubAppConfig   githubapp.Config `yaml:"github_app_config" json:"github_app_config"`           //nolint:tagliatelle
-	GHRepoTopic          string           `yaml:"github_repo_topic" json:"github_repo_topic"`           //nolint:tagliatelle
-	GHRepoWhitelist      []string         `yaml:"github_repo_whitelist" json:"github_repo_whitelist"`   //nolint:tagliatelle
-	SubFolderParallelism int              `yaml:"sub_folder_parallelism" json:"sub_folder_parallelism"` //nolint:tagliatelle
+	GithubHubAppConfig   githubapp.Config               `yaml:"github_app_config" json:"github_app_config"`           //nolint:tagliatelle
+	GHRepoTopic          string                         `yaml:"github_repo_topic" json:"github_repo_topic"`           //nolint:tagliatelle
+	GHRepoWhitelist      []string                       `yaml:"github_repo_whitelist" json:"github_repo_whitelist"`   //nolint:tagliatelle
+	SubFolderParallelism int                            `yaml:"sub_folder_parallelism" json:"sub_folder_parallelism"` //nolint:tagliatelle
+	Plugins              []plugin.Config                `yaml:"plugins" json:"plugins"`
+	AllowRepoPlugins     bool                           `yaml:"allow_repo_plugins" json:"allow_repo_plugins"`         //nolint:tagliatelle
+	FullScan             bool                           `yaml:"full_scan" json:"full_scan"`                           //nolint:tagliatelle
+	ProviderInstallation terraform.ProviderInstallation `yaml:"provider_installation" json:"provider_installation"`   //nolint:tagliatelle
+	TerraformVersionsDir string                         `yaml:"terraform_versions_dir" json:"terraform_versions_dir"` //nolint:tagliatelle
+	CheckTimeout         time.Duration                  `yaml:"check_timeout" json:"check_timeout"`                   //nolint:tagliatelle
+	EventTimeout         time.Duration                  `yaml:"event_timeout" json:"event_timeout"`                   //nolint:tagliatelle
+	QueueSize            int                            `yaml:"queue_size" json:"queue_size"`                         //nolint:tagliatelle
+	QueueWorkers         int                            `yaml:"queue_workers" json:"queue_workers"`                   //nolint:tagliatelle
+	QueuePath            string                         `yaml:"queue_path" json:"queue_path"`                         //nolint:tagliatelle
+	ShutdownGracePeriod  time.Duration                  `yaml:"shutdown_grace_period" json:"shutdown_grace_period"`   //nolint:tagliatelle
+	ListenAddress        string                         `yaml:"listen_address" json:"listen_address"`                 //nolint:tagliatelle
+	ListenPort           int                            `yaml:"listen_port" json:"listen_port"`                       //nolint:tagliatelle
+	TLSCertFile          string                         `yaml:"tls_cert_file" json:"tls_cert_file"`                   //nolint:tagliatelle
+	TLSKeyFile           string                         `yaml:"tls_key_file" json:"tls_key_file"`                     //nolint:tagliatelle
+	BasePath             string                         `yaml:"base_path" json:"base_path"`                           //nolint:tagliatelle
+	PrivateKeyPath       string                         `yaml:"private_key_path" json:"private_key_path"`             //nolint:tagliatelle
 }
 
-func LoadConfig() *Config {
-	confLocation := os.Getenv("APP_CONF")
-	if confLocation == "" {
-		confLocation = "conf.yml"
+const (
+	DefaultListenPort = 8000
+	maxListenPort     = 65535
+	// DefaultPath is the config file read when APP_CONF is not set, if it exists
+	DefaultPath = "conf.yml"
+
+	// DefaultCheckTimeout and DefaultEventTimeout are used when no timeout is configured
+	DefaultCheckTimeout        = 10 * time.Minute
+	DefaultEventTimeout        = 30 * time.Minute
+	DefaultQueueSize           = 100
+	DefaultQueueWorkers        = 4
+	DefaultShutdownGracePeriod = 20 * time.Second
+)
+
+// Path returns the config file of APP_CONF, or DefaultPath if it exists. It is empty when the
+// config is given by the env vars only.
+func Path() string {
+	if path := os.Getenv("APP_CONF"); path != "" {
+		return path
 	}
+	if _, err := os.Stat(DefaultPath); err == nil {
+		return DefaultPath
+	}
+	return ""
+}
+
+// Load loads the config file of path, if not empty, then overrides its fields by the env vars
+// found by lookupEnv and by flags, and validates it.
+func Load(path string, lookupEnv func(string) (string, bool), flags Overrides) (*Config, error) {
+	newConfig := Config{
+		CheckTimeout: DefaultCheckTimeout,
+		EventTimeout: DefaultEventTimeout,
+		QueueSize:    DefaultQueueSize,
+		QueueWorkers: DefaultQueueWorkers,
 
-	data, err := os.ReadFile(confLocation)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Error loading config file")
+		ShutdownGracePeriod: DefaultShutdownGracePeriod,
+		ListenPort:          DefaultListenPort,
 	}
-	var newConfig Config
-	if err := yaml.Unmarshal(data, &newConfig); err != nil {
-		log.Fatal().Err(err).Msg("Error Unmarshal config file")
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error loading config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &newConfig); err != nil {
+			return nil, fmt.Errorf("error unmarshalling config file %s: %w", path, err)
+		}
+	}
+
+	if err := EnvOverrides(lookupEnv).Apply(&newConfig); err != nil {
+		return nil, err
+	}
+	if err := flags.Apply(&newConfig); err != nil {
+		return nil, err
+	}
+
+	if newConfig.PrivateKeyPath != "" {
+		if newConfig.GithubHubAppConfig.App.PrivateKey != "" {
+			return nil, errors.ConfigNotValidError("you must provide only one of private_key_path field / github_app_config.app.private_key field")
+		}
+		key, err := os.ReadFile(newConfig.PrivateKeyPath)
+		if err != nil {
+			return nil, errors.ConfigNotValidError(fmt.Sprintf("error reading private_key_path: %v", err))
+		}
+		newConfig.GithubHubAppConfig.App.PrivateKey = string(key)
 	}
 
-	errors := validateConfig(&newConfig)
-	if len(errors) > 0 {
-		log.Fatal().Errs("errors", errors).Msg("config validation error")
+	if errs := validateConfig(&newConfig); len(errs) > 0 {
+		return nil, stderrors.Join(errs...)
 	}
+	return &newConfig, nil
+}
 
-	return &newConfig
+func validateListen(c *Config) []error {
+	errs := []error{}
+	if c.ListenPort <= 0 || c.ListenPort > maxListenPort {
+		errs = append(errs, errors.ConfigNotValidError(fmt.Sprintf("listen_port %d is not a valid port", c.ListenPort)))
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.ConfigNotValidError("you must provide both tls_cert_file and tls_key_file fields to enable TLS"))
+	}
+	if c.BasePath != "" && !strings.HasPrefix(c.BasePath, "/") {
+		errs = append(errs, errors.ConfigNotValidError(fmt.Sprintf("base_path %s must start with /", c.BasePath)))
+	}
+	return errs
 }
 
 func validateConfig(c *Config) []error {
@@ -58,5 +143,18 @@ func validateConfig(c *Config) []error {
 	if c.SubFolderParallelism == 0 {
 		errs = append(errs, errors.ConfigNotValidError("you must provide sub_folder_parallelism field"))
 	}
+
+	errs = append(errs, validateListen(c)...)
+
+	if c.QueueSize <= 0 || c.QueueWorkers <= 0 {
+		errs = append(errs, errors.ConfigNotValidError("queue_size and queue_workers fields must be positive"))
+	}
+
+	// The names of the built-in checks are only known by the server, which checks them on startup
+	for _, p := range c.Plugins {
+		if err := p.Validate(); err != nil {
+			errs = append(errs, errors.ConfigNotValidError(fmt.Sprintf("invalid plugin in plugins field: %v", err)))
+		}
+	}
 	return errs
 }
@@ -0,0 +1,192 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/terraform-tools/terraform-checker/pkg/config"
+)
+
+const validConfig = `
+github_app_config:
+  web_url: https://github.com
+  app:
+    integration_id: 1
+    webhook_secret: secret
+    private_key: key
+  oauth:
+    client_id: id
+    client_secret: secret
+sub_folder_parallelism: 2
+github_repo_whitelist: [repo]
+`
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Error writing %s: %v", name, err)
+	}
+	return path
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	path := writeFile(t, "conf.yml", validConfig)
+
+	c, err := config.Load(path, env(nil), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.GithubHubAppConfig.App.IntegrationID != 1 || c.SubFolderParallelism != 2 {
+		t.Errorf("Config not loaded from the file: %+v", c)
+	}
+	if c.ListenPort != config.DefaultListenPort {
+		t.Errorf("Expected default listen port, got %d", c.ListenPort)
+	}
+	if c.CheckTimeout != config.DefaultCheckTimeout || c.QueueWorkers != config.DefaultQueueWorkers {
+		t.Errorf("Expected default check timeout and queue workers, got %s and %d", c.CheckTimeout, c.QueueWorkers)
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	t.Parallel()
+	path := writeFile(t, "conf.yml", validConfig)
+
+	c, err := config.Load(path, env(map[string]string{
+		"TF_CHECKER_SUB_FOLDER_PARALLELISM":               "4",
+		"TF_CHECKER_LISTEN_PORT":                          "9000",
+		"TF_CHECKER_GITHUB_REPO_WHITELIST":                "repo-a, repo-b",
+		"TF_CHECKER_CHECK_TIMEOUT":                        "1m30s",
+		"TF_CHECKER_GITHUB_APP_CONFIG_APP_INTEGRATION_ID": "42",
+		"TF_CHECKER_PLUGINS":                              "[{name: lint, command: lint.sh}]",
+	}), config.Overrides{
+		"listen_port": "9001",
+		"full_scan":   "true",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.SubFolderParallelism != 4 {
+		t.Errorf("Expected sub_folder_parallelism overridden by env, got %d", c.SubFolderParallelism)
+	}
+	if c.ListenPort != 9001 {
+		t.Errorf("Expected listen_port overridden by flag, got %d", c.ListenPort)
+	}
+	if !c.FullScan {
+		t.Error("Expected full_scan overridden by flag")
+	}
+	if !reflect.DeepEqual(c.GHRepoWhitelist, []string{"repo-a", "repo-b"}) {
+		t.Errorf("Expected whitelist overridden by env, got %v", c.GHRepoWhitelist)
+	}
+	if c.CheckTimeout != 90*time.Second {
+		t.Errorf("Expected check_timeout overridden by env, got %s", c.CheckTimeout)
+	}
+	if c.GithubHubAppConfig.App.IntegrationID != 42 {
+		t.Errorf("Expected integration_id overridden by env, got %d", c.GithubHubAppConfig.App.IntegrationID)
+	}
+	if len(c.Plugins) != 1 || c.Plugins[0].Name != "lint" {
+		t.Errorf("Expected plugins overridden by env, got %+v", c.Plugins)
+	}
+}
+
+func TestLoadEnvOnly(t *testing.T) {
+	t.Parallel()
+	c, err := config.Load("", env(map[string]string{
+		"TF_CHECKER_GITHUB_APP_CONFIG_V3_API_URL":            "https://api.github.com",
+		"TF_CHECKER_GITHUB_APP_CONFIG_APP_INTEGRATION_ID":    "1",
+		"TF_CHECKER_GITHUB_APP_CONFIG_APP_WEBHOOK_SECRET":    "secret",
+		"TF_CHECKER_GITHUB_APP_CONFIG_APP_PRIVATE_KEY":       "key",
+		"TF_CHECKER_GITHUB_APP_CONFIG_OAUTH_CLIENT_ID":       "id",
+		"TF_CHECKER_GITHUB_APP_CONFIG_OAUTH_CLIENT_SECRET":   "secret",
+		"TF_CHECKER_SUB_FOLDER_PARALLELISM":                  "1",
+		"TF_CHECKER_PROVIDER_INSTALLATION_NETWORK_MIRROR":    "https://mirror",
+		"TF_CHECKER_PROVIDER_INSTALLATION_PLUGIN_CACHE_DIR":  "/cache",
+		"TF_CHECKER_PROVIDER_INSTALLATION_FILESYSTEM_MIRROR": "",
+	}), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.ProviderInstallation.NetworkMirror != "https://mirror" || c.ProviderInstallation.PluginCacheDir != "/cache" {
+		t.Errorf("Expected provider_installation from env, got %+v", c.ProviderInstallation)
+	}
+}
+
+func TestLoadPrivateKeyPath(t *testing.T) {
+	t.Parallel()
+	keyPath := writeFile(t, "key.pem", "file key")
+	path := writeFile(t, "conf.yml", strings.Replace(validConfig, "private_key: key", "", 1))
+
+	c, err := config.Load(path, env(map[string]string{"TF_CHECKER_PRIVATE_KEY_PATH": keyPath}), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.GithubHubAppConfig.App.PrivateKey != "file key" {
+		t.Errorf("Expected private key read from private_key_path, got %q", c.GithubHubAppConfig.App.PrivateKey)
+	}
+
+	if _, err := config.Load(path, env(nil), config.Overrides{"private_key_path": filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("Expected error reading a missing private_key_path")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Parallel()
+	path := writeFile(t, "conf.yml", validConfig)
+	keyPath := writeFile(t, "key.pem", "file key")
+
+	tests := map[string]struct {
+		path  string
+		flags config.Overrides
+		err   string
+	}{
+		"missing file":         {path: filepath.Join(t.TempDir(), "missing.yml"), err: "error loading config file"},
+		"invalid file":         {path: writeFile(t, "invalid.yml", "queue_size: [1]"), err: "error unmarshalling config file"},
+		"invalid override":     {path: path, flags: config.Overrides{"queue_size": "many"}, err: "invalid override of queue_size"},
+		"unknown override":     {path: path, flags: config.Overrides{"github_app_config.unknown": "1"}, err: "invalid override of github_app_config.unknown"},
+		"invalid config":       {path: path, flags: config.Overrides{"listen_port": "0", "base_path": "api"}, err: "base_path api must start with /"},
+		"private key and path": {path: path, flags: config.Overrides{"private_key_path": keyPath}, err: "only one of private_key_path"},
+		"missing config":       {path: "", err: "you must provide sub_folder_parallelism field"},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := config.Load(test.path, env(nil), test.flags)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	t.Parallel()
+	fields := config.Fields()
+	for _, path := range []string{"github_app_config.app.private_key", "provider_installation.network_mirror", "plugins", "private_key_path"} {
+		found := false
+		for _, f := range fields {
+			found = found || f == path
+		}
+		if !found {
+			t.Errorf("Expected field %s in %v", path, fields)
+		}
+	}
+	if name := config.EnvName("github_app_config.app.integration_id"); name != "TF_CHECKER_GITHUB_APP_CONFIG_APP_INTEGRATION_ID" {
+		t.Errorf("Unexpected env name %s", name)
+	}
+	if name := config.FlagName("github_app_config.app.integration_id"); name != "github-app-config-app-integration-id" {
+		t.Errorf("Unexpected flag name %s", name)
+	}
+}
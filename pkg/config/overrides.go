@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/terraform-tools/terraform-checker/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix prefixes the env vars overriding the config fields, e.g.
+// TF_CHECKER_GITHUB_APP_CONFIG_APP_INTEGRATION_ID overrides github_app_config.app.integration_id.
+const EnvPrefix = "TF_CHECKER_"
+
+// Overrides are values of config fields by the path of their yaml keys, e.g.
+// github_app_config.app.integration_id. Lists of strings are comma separated, the fields that are
+// neither strings nor lists of strings, e.g. durations or plugins, are given as yaml.
+type Overrides map[string]string
+
+// Fields returns the paths of the config fields that can be overridden.
+func Fields() []string {
+	return fieldPaths(reflect.TypeOf(Config{}), "")
+}
+
+func fieldPaths(t reflect.Type, prefix string) []string {
+	paths := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := yamlName(f)
+		if name == "" {
+			continue
+		}
+		if f.Type.Kind() == reflect.Struct {
+			paths = append(paths, fieldPaths(f.Type, prefix+name+".")...)
+			continue
+		}
+		paths = append(paths, prefix+name)
+	}
+	return paths
+}
+
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if !f.IsExported() || name == "-" {
+		return ""
+	}
+	return name
+}
+
+// EnvName returns the env var overriding the field of path.
+func EnvName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// FlagName returns the flag overriding the field of path.
+func FlagName(path string) string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(path)
+}
+
+// EnvOverrides returns the overrides set in the env vars found by lookup, e.g. os.LookupEnv.
+func EnvOverrides(lookup func(string) (string, bool)) Overrides {
+	o := Overrides{}
+	for _, path := range Fields() {
+		if value, ok := lookup(EnvName(path)); ok {
+			o[path] = value
+		}
+	}
+	return o
+}
+
+// Apply sets the overridden fields of c.
+func (o Overrides) Apply(c *Config) error {
+	paths := make([]string, 0, len(o))
+	for path := range o {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		f := field(reflect.ValueOf(c).Elem(), strings.Split(path, "."))
+		if !f.IsValid() {
+			return errors.ConfigNotValidError(fmt.Sprintf("invalid override of %s: unknown field", path))
+		}
+		if err := setValue(f, o[path]); err != nil {
+			return errors.ConfigNotValidError(fmt.Sprintf("invalid override of %s: %v", path, err))
+		}
+	}
+	return nil
+}
+
+// field returns the field of v at the path of keys, an invalid value if there is none.
+func field(v reflect.Value, keys []string) reflect.Value {
+	for i := 0; i < v.NumField(); i++ {
+		if yamlName(v.Type().Field(i)) != keys[0] {
+			continue
+		}
+		f := v.Field(i)
+		if len(keys) == 1 {
+			return f
+		}
+		if f.Kind() != reflect.Struct {
+			break
+		}
+		return field(f, keys[1:])
+	}
+	return reflect.Value{}
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "["):
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+		return nil
+	default:
+		return yaml.UnmarshalStrict([]byte(value), v.Addr().Interface())
+	}
+}
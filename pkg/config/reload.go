@@ -0,0 +1,202 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin"
+)
+
+// DefaultReloadInterval is the interval at which the config file is checked for changes.
+const DefaultReloadInterval = 10 * time.Second
+
+// ReloadableFields are the fields of the config applied on a reload, as they are read for each
+// event. The others are read on startup and need a restart of the server.
+func ReloadableFields() []string {
+	return []string{
+		"github_repo_topic",
+		"github_repo_whitelist",
+		"sub_folder_parallelism",
+		"allow_repo_plugins",
+		"full_scan",
+		"check_timeout",
+		"event_timeout",
+	}
+}
+
+// Change is a field whose value changed between two configs.
+type Change struct {
+	Path     string
+	Previous string
+	Next     string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, c.Previous, c.Next)
+}
+
+// Diff returns the fields changed from previous to next. The values of the secrets are masked,
+// and only the names of the plugins are given as their args commonly hold tokens.
+func Diff(previous, next *Config) []Change {
+	changes := []Change{}
+	for _, path := range Fields() {
+		keys := strings.Split(path, ".")
+		prev := field(reflect.ValueOf(previous).Elem(), keys).Interface()
+		cur := field(reflect.ValueOf(next).Elem(), keys).Interface()
+		if reflect.DeepEqual(prev, cur) {
+			continue
+		}
+		changes = append(changes, Change{Path: path, Previous: describe(path, prev), Next: describe(path, cur)})
+	}
+	return changes
+}
+
+// describe returns the value of the field of path as logged by Diff.
+func describe(path string, value interface{}) string {
+	if isSecret(path) {
+		return "***"
+	}
+	if plugins, ok := value.([]plugin.Config); ok {
+		names := make([]string, 0, len(plugins))
+		for _, p := range plugins {
+			names = append(names, p.Name)
+		}
+		return fmt.Sprintf("%v", names)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func isSecret(path string) bool {
+	return strings.HasSuffix(path, "private_key") || strings.HasSuffix(path, "secret")
+}
+
+func isReloadable(path string) bool {
+	for _, p := range ReloadableFields() {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Watcher reloads the config when its file changes or when the server gets SIGHUP. A reloaded
+// config is applied only if it is valid, the current one is kept otherwise.
+type Watcher struct {
+	path  string
+	flags Overrides
+
+	lock    sync.Mutex
+	current *Config
+	modTime time.Time
+}
+
+// NewWatcher loads the config of the file of path, overridden by the env vars and by flags like by
+// Load, to be reloaded by the Watcher.
+func NewWatcher(path string, flags Overrides) (*Watcher, error) {
+	modTime := fileModTime(path)
+	c, err := Load(path, os.LookupEnv, flags)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{path: path, flags: flags, current: c, modTime: modTime}, nil
+}
+
+// Current returns the config last applied.
+func (w *Watcher) Current() *Config {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.current
+}
+
+// Run reloads the config until ctx is done, giving each changed config to apply.
+func (w *Watcher) Run(ctx context.Context, apply func(*Config)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(DefaultReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Info().Msg("Got SIGHUP, reloading config")
+		case <-ticker.C:
+			if !w.changed() {
+				continue
+			}
+			log.Info().Msgf("Config file %s changed, reloading config", w.path)
+		}
+
+		next, changes, err := w.Reload()
+		if err != nil {
+			log.Error().Err(err).Msg("Error reloading config, keeping the current one")
+			continue
+		}
+		if len(changes) > 0 {
+			apply(next)
+		}
+	}
+}
+
+// Reload loads the config again, and makes it the current one if it is valid. Only the
+// ReloadableFields are changed, the changes of the others are logged as needing a restart.
+// It returns the current config and the changes applied.
+func (w *Watcher) Reload() (*Config, []Change, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.modTime = fileModTime(w.path)
+	loaded, err := Load(w.path, os.LookupEnv, w.flags)
+	if err != nil {
+		return w.current, nil, err
+	}
+
+	next := *w.current
+	for _, path := range ReloadableFields() {
+		keys := strings.Split(path, ".")
+		field(reflect.ValueOf(&next).Elem(), keys).Set(field(reflect.ValueOf(loaded).Elem(), keys))
+	}
+
+	for _, change := range Diff(w.current, loaded) {
+		if !isReloadable(change.Path) {
+			log.Warn().Msgf("Config changed %s, restart the server to apply it", change)
+		}
+	}
+	changes := Diff(w.current, &next)
+	for _, change := range changes {
+		log.Info().Msgf("Config changed %s", change)
+	}
+	if len(changes) > 0 {
+		w.current = &next
+	}
+	return w.current, changes, nil
+}
+
+func (w *Watcher) changed() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return !fileModTime(w.path).Equal(w.modTime)
+}
+
+// fileModTime returns the modification time of the file, following symlinks as the files of the
+// Kubernetes config maps are.
+func fileModTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
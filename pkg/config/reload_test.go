@@ -0,0 +1,101 @@
+package config_test
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/terraform-tools/terraform-checker/pkg/config"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin"
+)
+
+func TestWatcherReload(t *testing.T) {
+	t.Parallel()
+	path := writeFile(t, "conf.yml", validConfig)
+	w, err := config.NewWatcher(path, config.Overrides{"full_scan": "true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	initial := w.Current()
+
+	reloaded := validConfig + "listen_port: 9000\n"
+	reloaded = strings.Replace(reloaded, "sub_folder_parallelism: 2", "sub_folder_parallelism: 8", 1)
+	reloaded = strings.Replace(reloaded, "[repo]", "[repo, other]", 1)
+	if err := os.WriteFile(path, []byte(reloaded), 0o600); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+
+	next, changes, err := w.Reload()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	paths := []string{}
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	if !reflect.DeepEqual(paths, []string{"github_repo_whitelist", "sub_folder_parallelism"}) {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+	if next != w.Current() || next.SubFolderParallelism != 8 || !reflect.DeepEqual(next.GHRepoWhitelist, []string{"repo", "other"}) {
+		t.Errorf("Reloaded fields not applied: %+v", next)
+	}
+	if next.ListenPort != config.DefaultListenPort {
+		t.Errorf("Expected listen_port to need a restart, got %d", next.ListenPort)
+	}
+	if !next.FullScan {
+		t.Error("Expected flags to still override the reloaded config")
+	}
+	if initial.SubFolderParallelism != 2 {
+		t.Error("Expected the previous config to be left unchanged")
+	}
+
+	if _, changes, err := w.Reload(); err != nil || len(changes) > 0 {
+		t.Errorf("Expected no change reloading the same config, got %v, %v", changes, err)
+	}
+}
+
+func TestWatcherReloadInvalid(t *testing.T) {
+	t.Parallel()
+	path := writeFile(t, "conf.yml", validConfig)
+	w, err := config.NewWatcher(path, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	initial := w.Current()
+
+	if err := os.WriteFile(path, []byte(strings.Replace(validConfig, "sub_folder_parallelism: 2", "", 1)), 0o600); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	current, _, err := w.Reload()
+	if err == nil || !strings.Contains(err.Error(), "sub_folder_parallelism") {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+	if current != initial || w.Current() != initial {
+		t.Error("Expected the current config to be kept")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	previous := &config.Config{SubFolderParallelism: 1}
+	previous.GithubHubAppConfig.App.WebhookSecret = "old"
+	next := &config.Config{
+		SubFolderParallelism: 2,
+		Plugins:              []plugin.Config{{Name: "lint", Command: "lint.sh", Args: []string{"--token", "secret-token"}}},
+	}
+	next.GithubHubAppConfig.App.WebhookSecret = "new"
+
+	changes := config.Diff(previous, next)
+	expected := []config.Change{
+		{Path: "github_app_config.app.webhook_secret", Previous: "***", Next: "***"},
+		{Path: "sub_folder_parallelism", Previous: "1", Next: "2"},
+		{Path: "plugins", Previous: "[]", Next: "[lint]"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %v, got %v", expected, changes)
+	}
+	if s := changes[1].String(); s != "sub_folder_parallelism: 1 -> 2" {
+		t.Errorf("Unexpected change string %s", s)
+	}
+}
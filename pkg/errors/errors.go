@@ -12,3 +12,31 @@ func RepoNotValidError(msg string) error {
 func ConfigNotValidError(msg string) error {
 	return fmt.Errorf("%w : %s", errors.New("config not valid"), msg)
 }
+
+func PluginNotValidError(msg string) error {
+	return fmt.Errorf("%w : %s", errors.New("plugin not valid"), msg)
+}
+
+func CheckNotValidError(msg string) error {
+	return fmt.Errorf("%w : %s", errors.New("check not valid"), msg)
+}
+
+func FormatNotValidError(msg string) error {
+	return fmt.Errorf("%w : %s", errors.New("format not valid"), msg)
+}
+
+func SeverityNotValidError(msg string) error {
+	return fmt.Errorf("%w : %s", errors.New("severity not valid"), msg)
+}
+
+func TerraformVersionNotFoundError(msg string) error {
+	return fmt.Errorf("%w : %s", errors.New("terraform version not found"), msg)
+}
+
+func EngineNotValidError(msg string) error {
+	return fmt.Errorf("%w : %s", errors.New("engine not valid"), msg)
+}
+
+func RunCancelledError(msg string) error {
+	return fmt.Errorf("%w : %s", errors.New("run cancelled"), msg)
+}
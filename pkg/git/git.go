@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/terraform-tools/terraform-checker/pkg/metrics"
+	"github.com/terraform-tools/terraform-checker/pkg/utils"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -18,6 +20,7 @@ func CloneRepo(repoName string, hash string, headBranch string, ghToken string)
 	}
 
 	log.Debug().Msgf("Cloning repo %s into %s ...", repoName, dir)
+	defer metrics.ObserveClone(time.Now())
 	repo, err := git.PlainClone(dir, false, &git.CloneOptions{
 		URL: "https://x-access-token:" + ghToken + "@github.com/" + repoName,
 	})
@@ -81,3 +84,48 @@ func RemoveRepo(dir string) {
 		log.Error().Err(err).Msgf("Error while removing folder %v", dir)
 	}
 }
+
+// ChangedFiles returns the paths of the files changed between the merge base of
+// baseHash and headHash, and headHash, like the diff of a pull request.
+func ChangedFiles(repo *git.Repository, baseHash string, headHash string) ([]string, error) {
+	base, err := repo.CommitObject(plumbing.NewHash(baseHash))
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.CommitObject(plumbing.NewHash(headHash))
+	if err != nil {
+		return nil, err
+	}
+
+	mergeBases, err := base.MergeBase(head)
+	if err != nil {
+		return nil, err
+	}
+	if len(mergeBases) > 0 {
+		base = mergeBases[0]
+	}
+
+	baseTree, err := base.Tree()
+	if err != nil {
+		return nil, err
+	}
+	headTree, err := head.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := baseTree.Diff(headTree)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, change := range changes {
+		// Renamed files are changed in both their old and new location
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" && !utils.StrInSlice(files, name) {
+				files = append(files, name)
+			}
+		}
+	}
+	return files, nil
+}
@@ -3,19 +3,23 @@ package github
 import (
 	"context"
 	"fmt"
+	"path"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v56/github"
 	"github.com/rs/zerolog/log"
 	"github.com/shurcooL/githubv4"
+	"github.com/terraform-tools/terraform-checker/pkg/metrics"
 	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+	"github.com/terraform-tools/terraform-checker/pkg/utils"
 )
 
 const checkRunNamePrefix = "terraform-check "
 
-func (e *CheckEvent) CreateAggregatedCheckRun(checkType terraform.TfCheckType) (GhCheckRun, error) {
-	checkRunName := fmt.Sprintf("%s%v", checkRunNamePrefix, checkType.String())
+func (e *CheckEvent) CreateAggregatedCheckRun(checkName string) (GhCheckRun, error) {
+	checkRunName := fmt.Sprintf("%s%v", checkRunNamePrefix, checkName)
 	log.Info().Msgf("Create check run %s on repo %s PR %s", checkRunName, e.GetRepo().GetFullName(), e.GetPRURL())
 
 	cr, _, err := e.GetGhClient().Checks.CreateCheckRun(context.TODO(),
@@ -29,6 +33,7 @@ func (e *CheckEvent) CreateAggregatedCheckRun(checkType terraform.TfCheckType) (
 		})
 	if err != nil {
 		log.Error().Err(err).Msg("Error creating check run")
+		metrics.GithubAPIError("create_check_run")
 
 		return GhCheckRun{}, err
 	}
@@ -46,7 +51,10 @@ func (e *CheckEvent) UpdateAggregatedCheckRun(cr GhCheckRun, checks []terraform.
 
 	for _, check := range checks {
 		if !check.IsOK() {
-			checkRunState = githubv4.CheckConclusionStateFailure
+			// A failure is reported over a timeout, as it is known to need a change
+			if conclusion := check.FailureConclusion(); checkRunState != githubv4.CheckConclusionStateFailure {
+				checkRunState = conclusion
+			}
 			action = check.FixAction()
 		}
 
@@ -57,9 +65,14 @@ func (e *CheckEvent) UpdateAggregatedCheckRun(cr GhCheckRun, checks []terraform.
 	}
 
 	checkStatus := fmt.Sprintf("**Check Status:**  %s", CheckConclusionStateEmoji(checkRunState))
+	title := cr.Name
+	if engines := checksEngines(checks); len(engines) > 0 {
+		checkStatus += fmt.Sprintf("\n**Engine:** %s", strings.Join(engines, ", "))
+		title += fmt.Sprintf(" (%s)", strings.Join(engines, ", "))
+	}
 
 	cro := github.CheckRunOutput{
-		Title:       &cr.Name,
+		Title:       &title,
 		Summary:     &checkStatus,
 		Annotations: annotations,
 	}
@@ -88,5 +101,119 @@ func (e *CheckEvent) UpdateAggregatedCheckRun(cr GhCheckRun, checks []terraform.
 	)
 	if err != nil {
 		log.Error().Err(err).Msg("Error updating check run")
+		metrics.GithubAPIError("update_check_run")
+	}
+}
+
+// ListInProgressCheckRuns returns the check runs of the app appID on the commit that are still in progress.
+func (e *CheckEvent) ListInProgressCheckRuns(appID int64) ([]GhCheckRun, error) {
+	checkRuns := []GhCheckRun{}
+	opts := &github.ListCheckRunsOptions{
+		Status: github.String(strings.ToLower(string(githubv4.CheckStatusStateInProgress))),
+		AppID:  &appID,
+	}
+	for {
+		result, resp, err := e.GetGhClient().Checks.ListCheckRunsForRef(context.TODO(),
+			e.GetRepo().GetOwner().GetLogin(),
+			e.GetRepo().GetName(),
+			e.GetSHA(),
+			opts,
+		)
+		if err != nil {
+			log.Error().Err(err).Msg("Error listing check runs")
+			metrics.GithubAPIError("list_check_runs")
+			return nil, err
+		}
+		for _, cr := range result.CheckRuns {
+			if strings.HasPrefix(cr.GetName(), checkRunNamePrefix) {
+				checkRuns = append(checkRuns, GhCheckRun{Name: cr.GetName(), ID: cr.GetID()})
+			}
+		}
+		if resp.NextPage == 0 {
+			return checkRuns, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// ListCheckRunAnnotationDirs returns the dirs of the annotations of a check run, relative to the
+// repository, the root dir being empty.
+func (e *CheckEvent) ListCheckRunAnnotationDirs(checkRunID int64) ([]string, error) {
+	annotations := []*github.CheckRunAnnotation{}
+	opts := &github.ListOptions{}
+	for {
+		result, resp, err := e.GetGhClient().Checks.ListCheckRunAnnotations(context.TODO(),
+			e.GetRepo().GetOwner().GetLogin(),
+			e.GetRepo().GetName(),
+			checkRunID,
+			opts,
+		)
+		if err != nil {
+			log.Error().Err(err).Msg("Error listing check run annotations")
+			metrics.GithubAPIError("list_check_run_annotations")
+			return nil, err
+		}
+		annotations = append(annotations, result...)
+		if resp.NextPage == 0 {
+			return AnnotationDirs(annotations), nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// AnnotationDirs returns the dirs of the annotations, whose path is the relDir of their check
+// followed by a file.
+func AnnotationDirs(annotations []*github.CheckRunAnnotation) []string {
+	dirs := []string{}
+	for _, annotation := range annotations {
+		dir := strings.TrimPrefix(path.Dir(annotation.GetPath()), "/")
+		if dir == "." {
+			dir = ""
+		}
+		if !utils.StrInSlice(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// CancelAggregatedCheckRun concludes a check run as cancelled. GitHub only allows itself to
+// conclude a check run as stale.
+func (e *CheckEvent) CancelAggregatedCheckRun(cr GhCheckRun, reason error) {
+	summary := fmt.Sprintf("**Check Status:**  %s\n%s", CheckConclusionStateEmoji(githubv4.CheckConclusionStateCancelled), reason)
+	updateCheckRunOption := github.UpdateCheckRunOptions{
+		Name:   cr.Name,
+		Status: github.String(strings.ToLower(string(githubv4.CheckStatusStateCompleted))),
+		Output: &github.CheckRunOutput{
+			Title:   &cr.Name,
+			Summary: &summary,
+		},
+		Conclusion:  github.String(strings.ToLower(string(githubv4.CheckConclusionStateCancelled))),
+		CompletedAt: &github.Timestamp{Time: time.Now()},
+	}
+
+	log.Info().Msgf("Cancel check run %s on repo %s PR %s", cr.Name, e.GetRepo().GetFullName(), e.GetPRURL())
+	_, _, err := e.GetGhClient().Checks.UpdateCheckRun(
+		context.TODO(),
+		e.GetRepo().GetOwner().GetLogin(),
+		e.GetRepo().GetName(),
+		cr.ID,
+		updateCheckRunOption,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Error cancelling check run")
+		metrics.GithubAPIError("update_check_run")
+	}
+}
+
+// checksEngines returns the engines the checks were run with, sorted.
+func checksEngines(checks []terraform.TfCheck) []string {
+	engines := []string{}
+	for _, check := range checks {
+		if engine := string(check.Engine()); !utils.StrInSlice(engines, engine) {
+			engines = append(engines, engine)
+		}
 	}
+	sort.Strings(engines)
+	return engines
 }
@@ -0,0 +1,24 @@
+package github_test
+
+import (
+	"reflect"
+	"testing"
+
+	gh "github.com/google/go-github/v56/github"
+	"github.com/terraform-tools/terraform-checker/pkg/github"
+)
+
+func TestAnnotationDirs(t *testing.T) {
+	t.Parallel()
+	annotations := []*gh.CheckRunAnnotation{
+		{Path: gh.String("/main.tf")},
+		{Path: gh.String("envs/prod/.terraform.lock.hcl")},
+		{Path: gh.String("envs/prod/main.tf")},
+		{Path: gh.String("modules/vpc/versions.tf")},
+	}
+
+	dirs := github.AnnotationDirs(annotations)
+	if expected := []string{"", "envs/prod", "modules/vpc"}; !reflect.DeepEqual(dirs, expected) {
+		t.Errorf("Expected dirs %v, got %v", expected, dirs)
+	}
+}
@@ -1,6 +1,8 @@
 package github
 
 import (
+	"context"
+	"errors"
 	"strings"
 	"sync"
 
@@ -8,9 +10,10 @@ import (
 	"github.com/terraform-tools/terraform-checker/pkg/filter"
 	"github.com/terraform-tools/terraform-checker/pkg/git"
 	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+	"github.com/terraform-tools/terraform-checker/pkg/utils"
 )
 
-func (e *CheckEvent) runChecks(filters ...filter.Option) {
+func (e *CheckEvent) runChecks(ctx context.Context, filters ...filter.Option) {
 	tfCheckTypes := []string{}
 	dirFilter := ""
 	for _, f := range filters {
@@ -25,31 +28,106 @@ func (e *CheckEvent) runChecks(filters ...filter.Option) {
 			}
 		}
 	}
-	if len(tfCheckTypes) == 0 {
-		tfCheckTypes = terraform.AllTfCheckTypes()
-	}
 
-	_, dir, err := git.CloneRepo(e.GetRepo().GetFullName(), e.GetSHA(), e.GetBranch(), e.GetToken())
+	repo, dir, err := git.CloneRepo(e.GetRepo().GetFullName(), e.GetSHA(), e.GetBranch(), e.GetToken())
 	if err != nil {
 		log.Error().Err(err).Msg("Error cloning the repository")
 		return
 	}
 	defer git.RemoveRepo(dir)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		log.Info().Err(context.Cause(ctx)).Msgf("Checks of repo %s PR %s cancelled before creating the check runs", e.GetRepo().GetFullName(), e.GetPRURL())
+		return
+	}
 
-	// Create CheckRuns
-	checkRunMap := e.createCheckRuns(tfCheckTypes)
+	allTfDirs := terraform.FindAllTfDir(dir)
+	tfDirs := e.selectTfDirs(allTfDirs, dirFilter)
+	if len(tfCheckTypes) == 0 {
+		tfCheckTypes = e.registry.DefaultNames()
+		if e.allowRepoPlugins {
+			tfCheckTypes = append(tfCheckTypes, terraform.DirPluginNames(tfDirs)...)
+		}
+	}
+
+	// Create CheckRuns, for all of the dirs so that the same check runs are reported
+	// whatever the changed files
+	checkRunMap := e.createCheckRuns(e.resolveCheckNames(tfDirs, tfCheckTypes))
+
+	changedFiles := []string{}
+	if !e.fullScan && e.GetBaseSHA() != "" {
+		if changedFiles, err = git.ChangedFiles(repo, e.GetBaseSHA(), e.GetSHA()); err != nil {
+			log.Error().Err(err).Msg("Error computing the files changed by the pull request, checking every dir")
+		} else {
+			// Dirs calling a changed dir as a module are affected too
+			changed := terraform.ChangedTfDirs(allTfDirs, dir, changedFiles)
+			tfDirs = terraform.NewModuleGraph(allTfDirs).Affected(tfDirs, changed)
+			log.Info().Msgf("%d tfDirs affected by PR %s", len(tfDirs), e.GetPRURL())
+		}
+	}
 
 	// Execute checks
-	checks := e.executeChecks(dir, dirFilter, tfCheckTypes)
+	checks := e.executeChecks(ctx, dir, tfDirs, tfCheckTypes, changedFiles)
+
+	// Results of cancelled checks are not reported, newer ones are coming
+	if errors.Is(ctx.Err(), context.Canceled) {
+		e.cancelCheckRuns(checkRunMap, context.Cause(ctx))
+		return
+	}
 
 	// Update CheckRuns
 	e.updateCheckRuns(checkRunMap, checks)
 }
 
+// timeoutContext returns the context bounding the handling of the event.
+func (e *CheckEvent) timeoutContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.eventTimeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.eventTimeout)
+}
+
+// selectTfDirs returns the enabled TfDirs matching dirFilter.
+func (e *CheckEvent) selectTfDirs(tfDirs []*terraform.TfDir, dirFilter string) []*terraform.TfDir {
+	selected := []*terraform.TfDir{}
+	for _, tfDir := range tfDirs {
+		// If dirFilter is defined and current tfDir does not match, continue
+		if dirFilter != "" && !strings.Contains(tfDir.Path(), dirFilter) {
+			continue
+		}
+
+		// If tfDir is not enabled, continue
+		if !tfDir.IsEnabled() {
+			log.Info().Msgf("TfDir %s skipped, disabled via configuration", tfDir.Path())
+			continue
+		}
+		selected = append(selected, tfDir)
+	}
+	return selected
+}
+
+// resolveCheckNames returns the names of the checks run on at least one of the TfDirs,
+// dependencies included.
+func (e *CheckEvent) resolveCheckNames(tfDirs []*terraform.TfDir, tfCheckTypes []string) []string {
+	names := []string{}
+	for _, tfDir := range tfDirs {
+		resolved, err := terraform.DirRegistry(e.registry, tfDir, e.allowRepoPlugins, e.pluginManager).Resolve(tfCheckTypes)
+		if err != nil {
+			log.Error().Err(err).Msgf("error resolving checks of tfDir %s", tfDir.Path())
+			continue
+		}
+		for _, name := range resolved {
+			if !utils.StrInSlice(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
 func (e *CheckEvent) createCheckRuns(tfCheckTypes []string) map[string]GhCheckRun {
 	checkRunMap := make(map[string]GhCheckRun, len(tfCheckTypes))
 	for _, checkType := range tfCheckTypes {
-		newCr, err := e.CreateAggregatedCheckRun(terraform.TfCheckTypeFromString(checkType))
+		newCr, err := e.CreateAggregatedCheckRun(checkType)
 		if err != nil {
 			log.Error().Err(err).Msg("there was a problem while creating check_run")
 			continue
@@ -64,7 +142,7 @@ func (e *CheckEvent) updateCheckRuns(checkRunMap map[string]GhCheckRun, checks [
 		currentChecks := []terraform.TfCheck{}
 
 		for _, check := range checks {
-			if check.Type().String() == checkType {
+			if check.Name() == checkType {
 				currentChecks = append(currentChecks, check)
 			}
 		}
@@ -73,25 +151,24 @@ func (e *CheckEvent) updateCheckRuns(checkRunMap map[string]GhCheckRun, checks [
 	}
 }
 
-func (e *CheckEvent) executeChecks(dir, dirFilter string, tfCheckTypes []string) (checks []terraform.TfCheck) {
+func (e *CheckEvent) cancelCheckRuns(checkRunMap map[string]GhCheckRun, reason error) {
+	for _, checkRun := range checkRunMap {
+		e.CancelAggregatedCheckRun(checkRun, reason)
+	}
+}
+
+func (e *CheckEvent) executeChecks(ctx context.Context, dir string, tfDirs []*terraform.TfDir, tfCheckTypes []string, changedFiles []string) (checks []terraform.TfCheck) {
 	// SYNCHRONIZATION
 	// Wait group for waiting all tasks to be done in the end
 	var tasksDone sync.WaitGroup
 	// Chan allowing to run only n goroutines at the same time
 	currentlyRunning := make(chan int, e.subFolderParallelism)
+	var checksLock sync.Mutex
 
-	for _, tfDir := range terraform.FindAllTfDir(dir) {
+	for _, tfDir := range tfDirs {
 		tfDir := tfDir
-
-		// If dirFilter is defined and current tfDir does not match, continue
-		if dirFilter != "" && !strings.Contains(tfDir.Path(), dirFilter) {
-			continue
-		}
-
-		// If tfDir is not enabled, continue
-		if !tfDir.IsEnabled() {
-			log.Info().Msgf("TfDir %s skipped, disabled via configuration", tfDir.Path())
-			continue
+		if errors.Is(ctx.Err(), context.Canceled) {
+			break
 		}
 
 		currentlyRunning <- 1 // queue current task
@@ -100,10 +177,22 @@ func (e *CheckEvent) executeChecks(dir, dirFilter string, tfCheckTypes []string)
 			defer tasksDone.Done()
 
 			relDir := strings.TrimPrefix(strings.ReplaceAll(tfDir.Path(), dir, ""), "/")
-			for _, check := range terraform.GetTfChecks(tfDir.Path(), relDir, tfCheckTypes) {
-				check.Run()
-				checks = append(checks, check)
+			registry := terraform.DirRegistry(e.registry, tfDir, e.allowRepoPlugins, e.pluginManager)
+			tfChecks, err := registry.GetTfChecks(tfDir.Path(), relDir, tfCheckTypes)
+			if err != nil {
+				log.Error().Err(err).Msgf("error getting checks of tfDir %s", tfDir.Path())
+			}
+			terraform.SetChangedFiles(tfChecks, dir, changedFiles)
+			timeout := tfDir.CheckTimeout()
+			if timeout == 0 {
+				timeout = e.checkTimeout
+			}
+			for _, check := range tfChecks {
+				terraform.RunCheck(ctx, check, timeout)
 			}
+			checksLock.Lock()
+			checks = append(checks, tfChecks...)
+			checksLock.Unlock()
 			<-currentlyRunning // free up space for next one
 		}()
 	}
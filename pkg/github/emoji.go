@@ -7,7 +7,7 @@ func CheckConclusionStateEmoji(c githubv4.CheckConclusionState) string {
 	stateToEmoji := map[githubv4.CheckConclusionState]string{
 		githubv4.CheckConclusionStateActionRequired: ":question:",
 		githubv4.CheckConclusionStateTimedOut:       ":hourglass:",
-		githubv4.CheckConclusionStateCancelled:      "",
+		githubv4.CheckConclusionStateCancelled:      ":no_entry_sign:",
 		githubv4.CheckConclusionStateFailure:        ":x:",
 		githubv4.CheckConclusionStateSuccess:        ":heavy_check_mark:",
 		githubv4.CheckConclusionStateNeutral:        "",
@@ -1,21 +1,66 @@
 package github
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
 	"github.com/terraform-tools/terraform-checker/pkg/git"
 	"github.com/terraform-tools/terraform-checker/pkg/terraform"
 )
 
-const fmtCommitName = "terraform-checker fmt fix"
+const (
+	fmtCommitName      = "terraform-checker fmt fix"
+	lockfileCommitName = "terraform-checker lock file fix"
+)
 
-func (e *CheckEvent) fixFmt() error {
+func (e *CheckEvent) fixFmt(ctx context.Context) error {
 	repo, dir, err := git.CloneRepo(e.GetRepo().GetFullName(), e.GetSHA(), e.GetBranch(), e.GetToken())
 	if err != nil {
 		return err
 	}
 
-	if err := terraform.FixFmt(dir); err != nil {
+	if err := terraform.FixFmt(ctx, e.registry.ExecConfig(), dir); err != nil {
 		return err
 	}
 
 	return git.CommitAndPushRepo(fmtCommitName, repo)
 }
+
+// fixLockfile locks again the dirs annotated by the failing lockfile check run checkRunID.
+func (e *CheckEvent) fixLockfile(ctx context.Context, checkRunID int64) error {
+	relDirs, err := e.ListCheckRunAnnotationDirs(checkRunID)
+	if err != nil {
+		return err
+	}
+	if len(relDirs) == 0 {
+		log.Info().Msgf("No dir to fix for check run %d on repo %s PR %s", checkRunID, e.GetRepo().GetFullName(), e.GetPRURL())
+		return nil
+	}
+
+	repo, dir, err := git.CloneRepo(e.GetRepo().GetFullName(), e.GetSHA(), e.GetBranch(), e.GetToken())
+	if err != nil {
+		return err
+	}
+	defer git.RemoveRepo(dir)
+
+	if err := terraform.FixLockfile(ctx, e.registry.ExecConfig(), dir, relDirs); err != nil {
+		return err
+	}
+
+	return git.CommitAndPushRepo(lockfileCommitName, repo)
+}
+
+func (e *CheckEvent) fixPlugin(ctx context.Context, name string) error {
+	repo, dir, err := git.CloneRepo(e.GetRepo().GetFullName(), e.GetSHA(), e.GetBranch(), e.GetToken())
+	if err != nil {
+		return err
+	}
+	defer git.RemoveRepo(dir)
+
+	if err := terraform.FixPlugin(ctx, dir, name, e.plugins, e.allowRepoPlugins, e.pluginManager); err != nil {
+		return err
+	}
+
+	return git.CommitAndPushRepo(fmt.Sprintf("terraform-checker %s fix", name), repo)
+}
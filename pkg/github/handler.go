@@ -4,10 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"strings"
+	"sync/atomic"
 
 	"github.com/rs/zerolog/log"
 	"github.com/terraform-tools/terraform-checker/pkg/config"
+	"github.com/terraform-tools/terraform-checker/pkg/errors"
 	"github.com/terraform-tools/terraform-checker/pkg/filter"
+	"github.com/terraform-tools/terraform-checker/pkg/metrics"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin"
 	"github.com/terraform-tools/terraform-checker/pkg/terraform"
 
 	"github.com/google/go-github/v56/github"
@@ -16,39 +20,88 @@ import (
 
 type CheckHandler struct {
 	Client githubapp.ClientCreator
-	Config *config.Config
+	// Config is the config on startup, the current one is returned by GetConfig
+	Config   *config.Config
+	Plugins  *plugin.Manager
+	Registry *terraform.Registry
+	Runs     *RunTracker
+	// TfLintInitErr is the error of tflint --init, run by Init
+	TfLintInitErr error
+
+	reloaded atomic.Pointer[config.Config]
 }
 
 func (h *CheckHandler) Init() {
-	terraform.InitTfLint()
+	tfLintCtx, cancel := context.WithTimeout(context.Background(), config.DefaultCheckTimeout)
+	h.TfLintInitErr = terraform.InitTfLint(tfLintCtx)
+	cancel()
+	execConfig := terraform.ExecConfig{
+		ProviderInstallation: h.Config.ProviderInstallation,
+		VersionsDir:          h.Config.TerraformVersionsDir,
+	}
+	if err := execConfig.Setup(); err != nil {
+		log.Error().Err(err).Msg("error setting up terraform")
+	}
+
+	h.Plugins = plugin.NewManager()
+	if err := h.Plugins.Start(h.Config.Plugins); err != nil {
+		log.Error().Err(err).Msg("error starting plugins")
+	}
+
+	h.Runs = NewRunTracker()
+
+	h.Registry = terraform.DefaultRegistry()
+	h.Registry.SetExecConfig(execConfig)
+	if err := terraform.RegisterPlugins(h.Registry, h.Config.Plugins, h.Plugins); err != nil {
+		log.Error().Err(err).Msg("error registering plugins")
+	}
+}
+
+// GetConfig returns the current config, the last one given to SetConfig if any.
+func (h *CheckHandler) GetConfig() *config.Config {
+	if c := h.reloaded.Load(); c != nil {
+		return c
+	}
+	return h.Config
+}
+
+// SetConfig swaps the config of the handler, the events being handled keep the config they
+// started with.
+func (h *CheckHandler) SetConfig(c *config.Config) {
+	h.reloaded.Store(c)
+}
+
+// CheckCredentials checks that the app can authenticate to GitHub, by listing its installations.
+func (h *CheckHandler) CheckCredentials(ctx context.Context) error {
+	client, err := h.Client.NewAppClient()
+	if err != nil {
+		return err
+	}
+	if _, _, err := client.Apps.ListInstallations(ctx, &github.ListOptions{PerPage: 1}); err != nil {
+		metrics.GithubAPIError("list_installations")
+		return err
+	}
+	return nil
 }
 
 func (h *CheckHandler) Handles() []string {
 	return []string{"check_run", "check_suite", "pull_request"}
 }
 
-func (h *CheckHandler) Handle(_ context.Context, eventType, _ string, payload []byte) error { //nolint:cyclop
-	var ok bool
-	var event *CheckEvent
+func (h *CheckHandler) Handle(ctx context.Context, eventType, _ string, payload []byte) error { //nolint:cyclop
 	dirFilters := []filter.Option{}
 	checkTypeFilter := []filter.Option{}
 
-	switch eventType {
-	case "check_suite":
-		ok, event = h.getCheckSuiteEvent(payload)
-	case "pull_request":
-		ok, event = h.getPullRequestEvent(payload)
-	case "check_run":
-		ok, event = h.getCheckRunEvent(payload)
-	default:
-		return nil
-	}
-
 	// If current event is not valid, return
+	ok, event := h.getEvent(eventType, payload)
 	if !ok {
 		return nil
 	}
 
+	// The context is cancelled when the server stops
+	ctx, cancel := event.timeoutContext(ctx)
+	defer cancel()
+
 	switch e := event.GenericGithubEvent.(type) {
 	case CheckRunEvent:
 		// If the current event is a CheckRun, we need to compute filters
@@ -57,9 +110,12 @@ func (h *CheckHandler) Handle(_ context.Context, eventType, _ string, payload []
 		// If the current event is a requested action, execute it
 		if e.GetRequestedAction() != nil {
 			switch e.GetRequestedAction().Identifier {
-			case terraform.Fmt.String():
-				return event.fixFmt()
+			case terraform.Fmt:
+				return event.fixFmt(ctx)
+			case terraform.Lockfile:
+				return event.fixLockfile(ctx, e.GetCheckRun().GetID())
 			default:
+				return event.fixPlugin(ctx, e.GetRequestedAction().Identifier)
 			}
 		}
 
@@ -70,40 +126,85 @@ func (h *CheckHandler) Handle(_ context.Context, eventType, _ string, payload []
 	default:
 	}
 
-	event.runChecks(append(dirFilters, checkTypeFilter...)...)
+	// Checks of a previous commit of the pull request are cancelled
+	ctx, done := h.Runs.Start(ctx, event.GetRepo().GetFullName(), event.GetPRNumber(), event.GetSHA())
+	defer done()
+
+	event.runChecks(ctx, append(dirFilters, checkTypeFilter...)...)
 	return nil
 }
 
+// HandleInterrupted concludes the check runs left in progress by an event whose handling was
+// interrupted by a stop of the server, so that they can be re-run.
+func (h *CheckHandler) HandleInterrupted(_ context.Context, eventType, _ string, payload []byte) error {
+	ok, event := h.getEvent(eventType, payload)
+	if !ok {
+		return nil
+	}
+	// Requested actions don't create check runs
+	if e, isCheckRun := event.GenericGithubEvent.(CheckRunEvent); isCheckRun && e.GetRequestedAction() != nil {
+		return nil
+	}
+
+	checkRuns, err := event.ListInProgressCheckRuns(h.GetConfig().GithubHubAppConfig.App.IntegrationID)
+	if err != nil {
+		return err
+	}
+	for _, cr := range checkRuns {
+		event.CancelAggregatedCheckRun(cr, errors.RunCancelledError("interrupted by a restart of terraform-checker, please re-run the check"))
+	}
+	return nil
+}
+
+func (h *CheckHandler) getEvent(eventType string, payload []byte) (bool, *CheckEvent) {
+	switch eventType {
+	case "check_suite":
+		return h.getCheckSuiteEvent(payload)
+	case "pull_request":
+		return h.getPullRequestEvent(payload)
+	case "check_run":
+		return h.getCheckRunEvent(payload)
+	default:
+		return false, nil
+	}
+}
+
 func (h *CheckHandler) getCheckSuiteEvent(payload []byte) (bool, *CheckEvent) {
 	var event github.CheckSuiteEvent
 	if err := json.Unmarshal(payload, &event); err != nil {
 		log.Error().Err(err).Msg("Error unmarshal github payload")
+		metrics.EventDiscarded("check_suite", metrics.ReasonInvalidPayload)
 		return false, nil
 	}
 
-	newEvent, err := NewCheckEvent(h.Client, CheckSuiteEvent{&event}, h.Config)
+	config := h.GetConfig()
+	newEvent, err := NewCheckEvent(h.Client, CheckSuiteEvent{&event}, config, h.Registry, h.Plugins)
 
-	return err == nil && newEvent.IsValid(h.Config), newEvent
+	return err == nil && newEvent.IsValid(config), newEvent
 }
 
 func (h *CheckHandler) getCheckRunEvent(payload []byte) (bool, *CheckEvent) {
 	var event github.CheckRunEvent
 	if err := json.Unmarshal(payload, &event); err != nil {
 		log.Error().Err(err).Msg("Error unmarshal github payload")
+		metrics.EventDiscarded("check_run", metrics.ReasonInvalidPayload)
 		return false, nil
 	}
 
-	newEvent, err := NewCheckEvent(h.Client, CheckRunEvent{&event}, h.Config)
-	return err == nil && newEvent.IsValid(h.Config), newEvent
+	config := h.GetConfig()
+	newEvent, err := NewCheckEvent(h.Client, CheckRunEvent{&event}, config, h.Registry, h.Plugins)
+	return err == nil && newEvent.IsValid(config), newEvent
 }
 
 func (h *CheckHandler) getPullRequestEvent(payload []byte) (bool, *CheckEvent) {
 	var event github.PullRequestEvent
 	if err := json.Unmarshal(payload, &event); err != nil {
 		log.Error().Err(err).Msg("Error unmarshal github payload")
+		metrics.EventDiscarded("pull_request", metrics.ReasonInvalidPayload)
 		return false, nil
 	}
 
-	newEvent, err := NewCheckEvent(h.Client, PullRequestEvent{&event}, h.Config)
-	return err == nil && newEvent.IsValid(h.Config), newEvent
+	config := h.GetConfig()
+	newEvent, err := NewCheckEvent(h.Client, PullRequestEvent{&event}, config, h.Registry, h.Plugins)
+	return err == nil && newEvent.IsValid(config), newEvent
 }
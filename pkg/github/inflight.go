@@ -0,0 +1,80 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terraform-tools/terraform-checker/pkg/errors"
+)
+
+// pullRequestKey identifies a pull request among the ones of every repo.
+type pullRequestKey struct {
+	repo   string
+	number int
+}
+
+type inflightRun struct {
+	sha    string
+	cancel context.CancelCauseFunc
+}
+
+// RunTracker tracks the checks running on each pull request, so that the ones of a commit
+// are cancelled when a newer commit is pushed to the pull request.
+type RunTracker struct {
+	lock sync.Mutex
+	runs map[pullRequestKey][]*inflightRun
+}
+
+func NewRunTracker() *RunTracker {
+	return &RunTracker{runs: make(map[pullRequestKey][]*inflightRun)}
+}
+
+// Start registers a run of the checks of sha on the pull request number of repo, and cancels
+// the runs of the other commits of the pull request. The returned func must be called at the
+// end of the run. Runs of an unknown pull request, whose number is 0, are not tracked.
+func (t *RunTracker) Start(ctx context.Context, repo string, number int, sha string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	if number == 0 {
+		return ctx, func() { cancel(nil) }
+	}
+
+	key := pullRequestKey{repo: repo, number: number}
+	run := &inflightRun{sha: sha, cancel: cancel}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	kept := []*inflightRun{run}
+	for _, r := range t.runs[key] {
+		if r.sha == sha {
+			// Runs of the same commit check different things, e.g. a rerequested check run
+			kept = append(kept, r)
+			continue
+		}
+		log.Info().Msgf("Cancelling checks of commit %s on repo %s PR #%d, superseded by %s", r.sha, repo, number, sha)
+		r.cancel(errors.RunCancelledError(fmt.Sprintf("commit %s superseded by commit %s", r.sha, sha)))
+	}
+	t.runs[key] = kept
+
+	return ctx, func() {
+		cancel(nil)
+		t.done(key, run)
+	}
+}
+
+func (t *RunTracker) done(key pullRequestKey, run *inflightRun) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	kept := []*inflightRun{}
+	for _, r := range t.runs[key] {
+		if r != run {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == 0 {
+		delete(t.runs, key)
+		return
+	}
+	t.runs[key] = kept
+}
@@ -0,0 +1,51 @@
+package github_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/terraform-tools/terraform-checker/pkg/github"
+)
+
+func TestRunTracker(t *testing.T) {
+	t.Parallel()
+	tracker := github.NewRunTracker()
+
+	first, firstDone := tracker.Start(context.Background(), "org/repo", 1, "sha1")
+	defer firstDone()
+	rerun, rerunDone := tracker.Start(context.Background(), "org/repo", 1, "sha1")
+	defer rerunDone()
+	other, otherDone := tracker.Start(context.Background(), "org/repo", 2, "sha1")
+	defer otherDone()
+	if first.Err() != nil || rerun.Err() != nil || other.Err() != nil {
+		t.Fatalf("Expected runs of the same commit or of other PRs to go on")
+	}
+
+	newer, newerDone := tracker.Start(context.Background(), "org/repo", 1, "sha2")
+	if first.Err() == nil || rerun.Err() == nil {
+		t.Errorf("Expected runs of the previous commit to be cancelled")
+	}
+	if cause := context.Cause(first); cause == nil || cause.Error() != "run cancelled : commit sha1 superseded by commit sha2" {
+		t.Errorf("Unexpected cancellation cause %v", cause)
+	}
+	if newer.Err() != nil || other.Err() != nil {
+		t.Errorf("Expected the newer run and the runs of other PRs to go on")
+	}
+
+	// A finished run is not cancelled anymore
+	newerDone()
+	latest, latestDone := tracker.Start(context.Background(), "org/repo", 1, "sha3")
+	defer latestDone()
+	if !errors.Is(context.Cause(newer), context.Canceled) || latest.Err() != nil {
+		t.Errorf("Unexpected cancellation cause %v of a finished run", context.Cause(newer))
+	}
+
+	unknown, unknownDone := tracker.Start(context.Background(), "org/repo", 0, "sha1")
+	defer unknownDone()
+	_, unknownNewerDone := tracker.Start(context.Background(), "org/repo", 0, "sha2")
+	defer unknownNewerDone()
+	if unknown.Err() != nil {
+		t.Errorf("Expected runs of unknown PRs not to be tracked")
+	}
+}
@@ -3,12 +3,16 @@ package github
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/go-github/v56/github"
 	"github.com/palantir/go-githubapp/githubapp"
 	"github.com/rs/zerolog/log"
 	"github.com/terraform-tools/terraform-checker/pkg/config"
 	"github.com/terraform-tools/terraform-checker/pkg/errors"
+	"github.com/terraform-tools/terraform-checker/pkg/metrics"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
 	"github.com/terraform-tools/terraform-checker/pkg/utils"
 )
 
@@ -20,11 +24,12 @@ func (r *Repo) HasTopic(t string) bool {
 	return utils.StrInSlice(r.Topics, t)
 }
 
-func (r *Repo) IsValid(config *config.Config) (ok bool, err error) {
+func (r *Repo) IsValid(config *config.Config, eventType string) (ok bool, err error) {
 	if len(config.GHRepoWhitelist) > 0 {
 		if ok = utils.StrInSlice(config.GHRepoWhitelist, r.GetName()); !ok {
 			err = errors.RepoNotValidError(fmt.Sprintf("skipped repo %s because it's not in whitelist", r.GetFullName()))
 			log.Debug().Err(err).Msg("")
+			metrics.EventDiscarded(eventType, metrics.ReasonRepoWhitelist)
 			return
 		}
 	}
@@ -32,6 +37,7 @@ func (r *Repo) IsValid(config *config.Config) (ok bool, err error) {
 	if !r.HasTopic(config.GHRepoTopic) {
 		err = errors.RepoNotValidError(fmt.Sprintf("skipped repo %s because it does not have topic %s", r.GetFullName(), config.GHRepoTopic))
 		log.Debug().Err(err).Msg("")
+		metrics.EventDiscarded(eventType, metrics.ReasonRepoTopic)
 		return
 	}
 	return true, nil
@@ -46,11 +52,19 @@ type CheckEvent struct {
 	GenericGithubEvent
 	repo                 Repo
 	sha                  string
+	baseSHA              string
 	token                string
 	branch               string
 	prURL                string
 	ghClient             *github.Client
 	subFolderParallelism int
+	registry             *terraform.Registry
+	plugins              []plugin.Config
+	allowRepoPlugins     bool
+	pluginManager        *plugin.Manager
+	fullScan             bool
+	checkTimeout         time.Duration
+	eventTimeout         time.Duration
 }
 
 func (e *CheckEvent) GetRepo() *Repo {
@@ -61,6 +75,10 @@ func (e *CheckEvent) GetSHA() string {
 	return e.sha
 }
 
+func (e *CheckEvent) GetBaseSHA() string {
+	return e.baseSHA
+}
+
 func (e *CheckEvent) GetBranch() string {
 	return e.branch
 }
@@ -77,10 +95,10 @@ func (e *CheckEvent) GetGhClient() *github.Client {
 	return e.ghClient
 }
 
-func NewCheckEvent(clientCreator githubapp.ClientCreator, event GenericGithubEvent, config *config.Config) (*CheckEvent, error) {
+func NewCheckEvent(clientCreator githubapp.ClientCreator, event GenericGithubEvent, config *config.Config, registry *terraform.Registry, pluginManager *plugin.Manager) (*CheckEvent, error) {
 	repo := event.GetRepo()
 
-	if ok, err := repo.IsValid(config); !ok {
+	if ok, err := repo.IsValid(config, event.EventType()); !ok {
 		return nil, err
 	}
 
@@ -88,6 +106,7 @@ func NewCheckEvent(clientCreator githubapp.ClientCreator, event GenericGithubEve
 	client, err := clientCreator.NewAppClient()
 	if err != nil {
 		log.Error().Err(err).Msg("there was a problem while instantiating github client.")
+		metrics.EventDiscarded(event.EventType(), metrics.ReasonClientError)
 		return nil, err
 	}
 
@@ -99,12 +118,15 @@ func NewCheckEvent(clientCreator githubapp.ClientCreator, event GenericGithubEve
 	)
 	if err != nil {
 		log.Error().Err(err).Msg("there was a problem while creating installation token.")
+		metrics.GithubAPIError("create_installation_token")
+		metrics.EventDiscarded(event.EventType(), metrics.ReasonClientError)
 		return nil, err
 	}
 
 	client, err = clientCreator.NewInstallationClient(githubapp.GetInstallationIDFromEvent(event))
 	if err != nil {
 		log.Error().Err(err).Msg("there was a problem while creating installation client.")
+		metrics.EventDiscarded(event.EventType(), metrics.ReasonClientError)
 		return nil, err
 	}
 
@@ -112,11 +134,19 @@ func NewCheckEvent(clientCreator githubapp.ClientCreator, event GenericGithubEve
 		GenericGithubEvent:   event,
 		repo:                 repo,
 		sha:                  event.GetHeadSHA(),
+		baseSHA:              event.GetBaseSHA(),
 		token:                token.GetToken(),
 		branch:               event.GetHeadBranch(),
 		ghClient:             client,
 		prURL:                event.PrURL(),
 		subFolderParallelism: config.SubFolderParallelism,
+		registry:             registry,
+		plugins:              config.Plugins,
+		allowRepoPlugins:     config.AllowRepoPlugins,
+		pluginManager:        pluginManager,
+		fullScan:             config.FullScan,
+		checkTimeout:         config.CheckTimeout,
+		eventTimeout:         config.EventTimeout,
 	}, nil
 }
 
@@ -126,9 +156,15 @@ type GenericGithubEvent interface {
 
 	GetRepo() Repo
 	GetHeadSHA() string
+	// GetBaseSHA returns the base of the pull request, empty if unknown
+	GetBaseSHA() string
 	GetHeadBranch() string
 	IsValid(*config.Config) bool
 	PrURL() string
+	// GetPRNumber returns the number of the pull request, 0 if unknown
+	GetPRNumber() int
+	// EventType returns the type of the webhook event, e.g. check_suite
+	EventType() string
 }
 
 // Rename external struct to be able to extend them with interface func.
@@ -139,6 +175,10 @@ type (
 )
 
 // CheckSuiteEvent.
+func (e CheckSuiteEvent) EventType() string {
+	return "check_suite"
+}
+
 func (e CheckSuiteEvent) GetRepo() Repo {
 	return Repo{e.Repo}
 }
@@ -147,6 +187,13 @@ func (e CheckSuiteEvent) GetHeadSHA() string {
 	return e.GetCheckSuite().GetHeadSHA()
 }
 
+func (e CheckSuiteEvent) GetBaseSHA() string {
+	if prs := e.GetCheckSuite().PullRequests; len(prs) == 1 {
+		return prs[0].GetBase().GetSHA()
+	}
+	return ""
+}
+
 func (e CheckSuiteEvent) GetHeadBranch() string {
 	return e.GetCheckSuite().GetHeadBranch()
 }
@@ -154,15 +201,18 @@ func (e CheckSuiteEvent) GetHeadBranch() string {
 func (e CheckSuiteEvent) IsValid(c *config.Config) bool {
 	if e.GetCheckSuite().GetApp().GetID() != c.GithubHubAppConfig.App.IntegrationID {
 		log.Debug().Msgf("Discarding event check_suite: not related to this github app")
+		metrics.EventDiscarded(e.EventType(), metrics.ReasonOtherApp)
 		return false
 	}
 	if !utils.StrInSlice(getAuthorizedCheckSuiteActions(), e.GetAction()) {
 		log.Debug().Msgf("Discarding event check_suite %s", e.GetAction())
+		metrics.EventDiscarded(e.EventType(), metrics.ReasonAction)
 		return false
 	}
 
 	if len(e.GetCheckSuite().PullRequests) == 0 {
 		log.Debug().Msgf("Discarding event (not related to a PR)")
+		metrics.EventDiscarded(e.EventType(), metrics.ReasonNoPullRequest)
 		return false
 	}
 	return true
@@ -175,7 +225,18 @@ func (e CheckSuiteEvent) PrURL() string {
 	return ""
 }
 
+func (e CheckSuiteEvent) GetPRNumber() int {
+	if prs := e.GetCheckSuite().PullRequests; len(prs) == 1 {
+		return prs[0].GetNumber()
+	}
+	return 0
+}
+
 // CheckRunEvent.
+func (e CheckRunEvent) EventType() string {
+	return "check_run"
+}
+
 func (e CheckRunEvent) GetRepo() Repo {
 	return Repo{e.Repo}
 }
@@ -184,6 +245,13 @@ func (e CheckRunEvent) GetHeadSHA() string {
 	return e.GetCheckRun().GetHeadSHA()
 }
 
+func (e CheckRunEvent) GetBaseSHA() string {
+	if prs := e.GetCheckRun().PullRequests; len(prs) == 1 {
+		return prs[0].GetBase().GetSHA()
+	}
+	return ""
+}
+
 func (e CheckRunEvent) GetHeadBranch() string {
 	return e.GetCheckRun().GetCheckSuite().GetHeadBranch()
 }
@@ -191,14 +259,17 @@ func (e CheckRunEvent) GetHeadBranch() string {
 func (e CheckRunEvent) IsValid(c *config.Config) bool {
 	if e.GetCheckRun().GetApp().GetID() != c.GithubHubAppConfig.App.IntegrationID {
 		log.Debug().Msgf("Discarding event check_run: not related to this github app")
+		metrics.EventDiscarded(e.EventType(), metrics.ReasonOtherApp)
 		return false
 	}
 	if !utils.StrInSlice(getAuthorizedCheckRunActions(), e.GetAction()) {
 		log.Debug().Msgf("Discarding event check_suite %s", e.GetAction())
+		metrics.EventDiscarded(e.EventType(), metrics.ReasonAction)
 		return false
 	}
 	if len(e.GetCheckRun().PullRequests) == 0 {
 		log.Debug().Msgf("Discarding event (not related to a PR)")
+		metrics.EventDiscarded(e.EventType(), metrics.ReasonNoPullRequest)
 		return false
 	}
 	return true
@@ -211,7 +282,18 @@ func (e CheckRunEvent) PrURL() string {
 	return ""
 }
 
+func (e CheckRunEvent) GetPRNumber() int {
+	if prs := e.GetCheckRun().PullRequests; len(prs) == 1 {
+		return prs[0].GetNumber()
+	}
+	return 0
+}
+
 // PullRequestEvent.
+func (e PullRequestEvent) EventType() string {
+	return "pull_request"
+}
+
 func (e PullRequestEvent) GetRepo() Repo {
 	return Repo{e.Repo}
 }
@@ -220,6 +302,10 @@ func (e PullRequestEvent) GetHeadSHA() string {
 	return e.GetPullRequest().GetHead().GetSHA()
 }
 
+func (e PullRequestEvent) GetBaseSHA() string {
+	return e.GetPullRequest().GetBase().GetSHA()
+}
+
 func (e PullRequestEvent) GetHeadBranch() string {
 	return e.GetPullRequest().GetHead().GetRef()
 }
@@ -227,6 +313,7 @@ func (e PullRequestEvent) GetHeadBranch() string {
 func (e PullRequestEvent) IsValid(_ *config.Config) bool {
 	if !utils.StrInSlice(getAuthorizedPullRequestActions(), e.GetAction()) {
 		log.Debug().Msgf("Discarding event pull_request %s", e.GetAction())
+		metrics.EventDiscarded(e.EventType(), metrics.ReasonAction)
 		return false
 	}
 	return true
@@ -235,3 +322,7 @@ func (e PullRequestEvent) IsValid(_ *config.Config) bool {
 func (e PullRequestEvent) PrURL() string {
 	return e.GetPullRequest().GetURL()
 }
+
+func (e PullRequestEvent) GetPRNumber() int {
+	return e.GetPullRequest().GetNumber()
+}
@@ -0,0 +1,71 @@
+package local
+
+import (
+	"context"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+// applyFixes runs the fix of every failing check, and returns the TfDirs in which a fix
+// was applied. ok is false if some fixes failed.
+func applyFixes(ctx context.Context, tfDirs []*terraform.TfDir, registry *terraform.Registry, checks []terraform.TfCheck, allowRepoPlugins bool, pluginManager *plugin.Manager) (fixed []*terraform.TfDir, ok bool) {
+	ok = true
+	for _, tfDir := range tfDirs {
+		dirRegistry := terraform.DirRegistry(registry, tfDir, allowRepoPlugins, pluginManager)
+		fixedDir := false
+
+		for _, check := range checks {
+			if check.Dir() != tfDir.Path() || check.IsOK() {
+				continue
+			}
+
+			def, found := dirRegistry.Get(check.Name())
+			if !found || def.Fix == nil {
+				log.Info().Msgf("No fix available for check %s on tfDir %s", check.Name(), tfDir.Path())
+				continue
+			}
+			if err := def.Fix(ctx, dirRegistry.ExecConfig(), tfDir, check.RelDir()); err != nil {
+				log.Error().Err(err).Msgf("Error fixing check %s on tfDir %s", check.Name(), tfDir.Path())
+				ok = false
+				continue
+			}
+			fixedDir = true
+		}
+
+		if fixedDir {
+			fixed = append(fixed, tfDir)
+		}
+	}
+	return fixed, ok
+}
+
+// replaceDirChecks replaces the checks of the fixed TfDirs by the ones run after the fix,
+// keeping them sorted by dir.
+func replaceDirChecks(checks []terraform.TfCheck, fixed []*terraform.TfDir, rechecks []terraform.TfCheck) []terraform.TfCheck {
+	isFixed := func(dir string) bool {
+		for _, tfDir := range fixed {
+			if tfDir.Path() == dir {
+				return true
+			}
+		}
+		return false
+	}
+
+	replaced := []terraform.TfCheck{}
+	for _, check := range checks {
+		if !isFixed(check.Dir()) {
+			replaced = append(replaced, check)
+		}
+	}
+	for _, check := range rechecks {
+		if !check.IsOK() {
+			log.Warn().Msgf("Check %s still failing on tfDir %s after fix", check.Name(), check.Dir())
+		}
+	}
+	replaced = append(replaced, rechecks...)
+	sort.SliceStable(replaced, func(i, j int) bool { return replaced[i].Dir() < replaced[j].Dir() })
+	return replaced
+}
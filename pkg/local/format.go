@@ -0,0 +1,444 @@
+package local
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/shurcooL/githubv4"
+	"github.com/terraform-tools/terraform-checker/pkg/errors"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+	"github.com/terraform-tools/terraform-checker/pkg/utils"
+)
+
+// Output formats of the local mode.
+const (
+	FormatText       = "text"
+	FormatJSON       = "json"
+	FormatSARIF      = "sarif"
+	FormatJUnit      = "junit"
+	FormatCheckstyle = "checkstyle"
+)
+
+const (
+	toolName    = "terraform-checker"
+	toolURI     = "https://github.com/terraform-tools/terraform-checker"
+	sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Formats returns the supported output formats.
+func Formats() []string {
+	return []string{FormatText, FormatJSON, FormatSARIF, FormatJUnit, FormatCheckstyle}
+}
+
+// Output defines how the results of the local mode are rendered.
+type Output struct {
+	Format string
+	// File to write the results to, stdout if empty
+	File string
+}
+
+func (o Output) Validate() error {
+	if o.Format != "" && !utils.StrInSlice(Formats(), o.Format) {
+		return errors.FormatNotValidError(fmt.Sprintf("format %s is not one of %s", o.Format, strings.Join(Formats(), ", ")))
+	}
+	return nil
+}
+
+// WriteOutput renders the checks in the output format.
+func WriteOutput(checks []terraform.TfCheck, output Output) error {
+	w := io.Writer(os.Stdout)
+	if output.File != "" {
+		f, err := os.Create(output.File)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch output.Format {
+	case FormatJSON:
+		return renderJSON(w, checks)
+	case FormatSARIF:
+		return renderSARIF(w, checks)
+	case FormatJUnit:
+		return renderJUnit(w, checks)
+	case FormatCheckstyle:
+		return renderCheckstyle(w, checks)
+	default:
+		renderText(w, checks)
+		return nil
+	}
+}
+
+// annotationPath returns the path of an annotation relative to the checked dir.
+func annotationPath(annotation *github.CheckRunAnnotation) string {
+	return strings.TrimPrefix(annotation.GetPath(), "/")
+}
+
+func checkPath(check terraform.TfCheck) string {
+	if check.RelDir() == "" {
+		return "."
+	}
+	return check.RelDir()
+}
+
+// JSON
+
+type jsonAnnotation struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line,omitempty"` //nolint:tagliatelle
+	EndLine   int    `json:"end_line,omitempty"`   //nolint:tagliatelle
+	Level     string `json:"level"`
+	Title     string `json:"title,omitempty"`
+	Message   string `json:"message"`
+}
+
+type jsonCheck struct {
+	Dir         string           `json:"dir"`
+	Check       string           `json:"check"`
+	OK          bool             `json:"ok"`
+	Output      string           `json:"output,omitempty"`
+	Annotations []jsonAnnotation `json:"annotations"`
+}
+
+func renderJSON(w io.Writer, checks []terraform.TfCheck) error {
+	results := make([]jsonCheck, 0, len(checks))
+	for _, check := range checks {
+		result := jsonCheck{
+			Dir:         checkPath(check),
+			Check:       check.Name(),
+			OK:          check.IsOK(),
+			Output:      check.Output(),
+			Annotations: []jsonAnnotation{},
+		}
+		for _, annotation := range check.Annotations() {
+			result.Annotations = append(result.Annotations, jsonAnnotation{
+				Path:      annotationPath(annotation),
+				StartLine: annotation.GetStartLine(),
+				EndLine:   annotation.GetEndLine(),
+				Level:     annotation.GetAnnotationLevel(),
+				Title:     annotation.GetTitle(),
+				Message:   annotation.GetMessage(),
+			})
+		}
+		results = append(results, result)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+// SARIF 2.1.0
+
+type sarifReport struct {
+	Schema  string     `json:"$schema"` //nolint:tagliatelle
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine,omitempty"`
+}
+
+// sarifLevel converts a github annotation level to a SARIF level.
+func sarifLevel(level string) string {
+	switch level {
+	case strings.ToLower(string(githubv4.CheckAnnotationLevelFailure)):
+		return "error"
+	case strings.ToLower(string(githubv4.CheckAnnotationLevelNotice)):
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+func sarifLocationOf(path string, startLine, endLine int) []sarifLocation {
+	location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: path}}}
+	if startLine > 0 {
+		location.PhysicalLocation.Region = &sarifRegion{StartLine: startLine, EndLine: endLine}
+	}
+	return []sarifLocation{location}
+}
+
+func renderSARIF(w io.Writer, checks []terraform.TfCheck) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           toolName,
+			InformationURI: toolURI,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := []string{}
+	for _, check := range checks {
+		if !utils.StrInSlice(rules, check.Name()) {
+			rules = append(rules, check.Name())
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               check.Name(),
+				ShortDescription: sarifMessage{Text: fmt.Sprintf("%s check", check.Name())},
+			})
+		}
+		if check.IsOK() {
+			continue
+		}
+
+		annotations := check.Annotations()
+		for _, annotation := range annotations {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    check.Name(),
+				Level:     sarifLevel(annotation.GetAnnotationLevel()),
+				Message:   sarifMessage{Text: annotationMessage(annotation)},
+				Locations: sarifLocationOf(annotationPath(annotation), annotation.GetStartLine(), annotation.GetEndLine()),
+			})
+		}
+		// Checks failing without annotations are reported on their dir
+		if len(annotations) == 0 {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    check.Name(),
+				Level:     "error",
+				Message:   sarifMessage{Text: checkMessage(check)},
+				Locations: sarifLocationOf(checkPath(check), 0, 0),
+			})
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifReport{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+func annotationMessage(annotation *github.CheckRunAnnotation) string {
+	if annotation.GetTitle() == "" {
+		return annotation.GetMessage()
+	}
+	return fmt.Sprintf("%s: %s", annotation.GetTitle(), annotation.GetMessage())
+}
+
+func checkMessage(check terraform.TfCheck) string {
+	if out := strings.TrimSpace(check.Output()); out != "" {
+		return out
+	}
+	return fmt.Sprintf("%s check failed", check.Name())
+}
+
+// JUnit XML
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+// renderJUnit renders a test suite per check, with a test case per dir.
+func renderJUnit(w io.Writer, checks []terraform.TfCheck) error {
+	report := junitTestSuites{}
+	suites := map[string]*junitTestSuite{}
+	names := []string{}
+
+	for _, check := range checks {
+		suite, ok := suites[check.Name()]
+		if !ok {
+			suite = &junitTestSuite{Name: check.Name()}
+			suites[check.Name()] = suite
+			names = append(names, check.Name())
+		}
+
+		testCase := junitTestCase{
+			Name:      checkPath(check),
+			ClassName: fmt.Sprintf("%s.%s", toolName, check.Name()),
+		}
+		if check.IsOK() {
+			testCase.SystemOut = check.Output()
+		} else {
+			details := []string{}
+			for _, annotation := range check.Annotations() {
+				details = append(details, fmt.Sprintf("%s:%d: %s: %s", annotationPath(annotation), annotation.GetStartLine(), annotation.GetAnnotationLevel(), annotationMessage(annotation)))
+			}
+			details = append(details, check.Output())
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("%s check failed", check.Name()),
+				Type:    check.Name(),
+				Content: strings.Join(details, "\n"),
+			}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.Tests++
+		report.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	for _, name := range names {
+		report.Suites = append(report.Suites, *suites[name])
+	}
+
+	return writeXML(w, report)
+}
+
+// Checkstyle XML
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// checkstyleSeverity converts a github annotation level to a checkstyle severity.
+func checkstyleSeverity(level string) string {
+	switch level {
+	case strings.ToLower(string(githubv4.CheckAnnotationLevelFailure)):
+		return "error"
+	case strings.ToLower(string(githubv4.CheckAnnotationLevelNotice)):
+		return "info"
+	default:
+		return "warning"
+	}
+}
+
+func renderCheckstyle(w io.Writer, checks []terraform.TfCheck) error {
+	report := checkstyleReport{Version: "4.3"}
+	files := map[string]*checkstyleFile{}
+	names := []string{}
+
+	addError := func(name string, e checkstyleError) {
+		file, ok := files[name]
+		if !ok {
+			file = &checkstyleFile{Name: name}
+			files[name] = file
+			names = append(names, name)
+		}
+		file.Errors = append(file.Errors, e)
+	}
+
+	for _, check := range checks {
+		if check.IsOK() {
+			continue
+		}
+		source := fmt.Sprintf("%s.%s", toolName, check.Name())
+
+		annotations := check.Annotations()
+		for _, annotation := range annotations {
+			addError(annotationPath(annotation), checkstyleError{
+				Line:     annotation.GetStartLine(),
+				Severity: checkstyleSeverity(annotation.GetAnnotationLevel()),
+				Message:  annotationMessage(annotation),
+				Source:   source,
+			})
+		}
+		// Checks failing without annotations are reported on their dir
+		if len(annotations) == 0 {
+			addError(checkPath(check), checkstyleError{
+				Severity: "error",
+				Message:  checkMessage(check),
+				Source:   source,
+			})
+		}
+	}
+	for _, name := range names {
+		report.Files = append(report.Files, *files[name])
+	}
+
+	return writeXML(w, report)
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,117 @@
+package local_test
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/shurcooL/githubv4"
+	"github.com/terraform-tools/terraform-checker/pkg/local"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+type fakeCheck struct {
+	name        string
+	relDir      string
+	ok          bool
+	output      string
+	annotations []*github.CheckRunAnnotation
+}
+
+func (c *fakeCheck) Name() string             { return c.name }
+func (c *fakeCheck) Run(context.Context)      {}
+func (c *fakeCheck) Dir() string              { return "/repo/" + c.relDir }
+func (c *fakeCheck) RelDir() string           { return c.relDir }
+func (c *fakeCheck) Engine() terraform.Engine { return terraform.EngineTerraform }
+func (c *fakeCheck) IsOK() bool               { return c.ok }
+func (c *fakeCheck) Output() string           { return c.output }
+func (c *fakeCheck) FailureConclusion() githubv4.CheckConclusionState {
+	return githubv4.CheckConclusionStateFailure
+}
+func (c *fakeCheck) FixAction() *github.CheckRunAction         { return nil }
+func (c *fakeCheck) Annotations() []*github.CheckRunAnnotation { return c.annotations }
+
+func testChecks() []terraform.TfCheck {
+	return []terraform.TfCheck{
+		&fakeCheck{name: "fmt", relDir: "a", ok: false, output: "main.tf"},
+		&fakeCheck{name: "tflint", relDir: "a", ok: false, annotations: []*github.CheckRunAnnotation{{
+			Path:            github.String("a/main.tf"),
+			StartLine:       github.Int(3),
+			EndLine:         github.Int(4),
+			AnnotationLevel: github.String("warning"),
+			Title:           github.String("rule"),
+			Message:         github.String("message"),
+		}}},
+		&fakeCheck{name: "fmt", relDir: "b", ok: true},
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		format   string
+		contains []string
+		parse    func([]byte) error
+	}{
+		{
+			format:   local.FormatJSON,
+			contains: []string{`"start_line": 3`, `"check": "tflint"`},
+			parse:    func(data []byte) error { return json.Unmarshal(data, &[]any{}) },
+		}, {
+			format:   local.FormatSARIF,
+			contains: []string{`"version": "2.1.0"`, `"ruleId": "tflint"`, `"uri": "a/main.tf"`, `"startLine": 3`},
+			parse:    func(data []byte) error { return json.Unmarshal(data, &map[string]any{}) },
+		}, {
+			format:   local.FormatJUnit,
+			contains: []string{`<testsuites tests="3" failures="2">`, `<testcase name="b" classname="terraform-checker.fmt">`},
+			parse:    func(data []byte) error { return xml.Unmarshal(data, &struct{}{}) },
+		}, {
+			format:   local.FormatCheckstyle,
+			contains: []string{`<file name="a/main.tf">`, `<error line="3" severity="warning" message="rule: message" source="terraform-checker.tflint">`},
+			parse:    func(data []byte) error { return xml.Unmarshal(data, &struct{}{}) },
+		}, {
+			format:   local.FormatText,
+			contains: []string{"--- /repo/a", "--- /repo/b"},
+			parse:    func([]byte) error { return nil },
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+			file := path.Join(t.TempDir(), "output")
+			if err := local.WriteOutput(testChecks(), local.Output{Format: tc.format, File: file}); err != nil {
+				t.Fatalf("WriteOutput failed: %v", err)
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("Error reading output %v", err)
+			}
+			if err := tc.parse(data); err != nil {
+				t.Errorf("Output is not valid %s: %v", tc.format, err)
+			}
+			for _, s := range tc.contains {
+				if !strings.Contains(string(data), s) {
+					t.Errorf("Output does not contain %s:\n%s", s, data)
+				}
+			}
+		})
+	}
+}
+
+func TestOutputValidate(t *testing.T) {
+	t.Parallel()
+	if err := (local.Output{Format: "yaml"}).Validate(); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+	if err := (local.Output{Format: local.FormatSARIF}).Validate(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
@@ -1,34 +1,136 @@
 package local
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/rs/zerolog/log"
+	"github.com/terraform-tools/terraform-checker/pkg/errors"
 	"github.com/terraform-tools/terraform-checker/pkg/filter"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin"
 	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+	"github.com/terraform-tools/terraform-checker/pkg/utils"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
-func StartLocal(dir string, parallelism uint, checkTypes filter.TfCheckTypeFilter) {
-	// SYNCHRONIZATION
-	// Wait group for waiting all tasks to be done in the end
-	var tasksDone sync.WaitGroup
-	// Chan allowing to run only n goroutines at the same time
-	currentlyRunning := make(chan int, parallelism)
+// Exit codes of the local mode.
+const (
+	ExitOK = iota
+	ExitChecksFailed
+	ExitError
+	ExitNothingFound
+)
 
+// Options of the local mode.
+type Options struct {
+	Parallelism uint
+	Output      Output
+	// FailOn is the minimum severity of the failing checks making the run fail
+	FailOn string
+	// Fix applies the fixes of the failing checks in place, and runs the checks again
+	Fix                  bool
+	ProviderInstallation terraform.ProviderInstallation
+	// TerraformVersionsDir holds the installed terraform versions the one of each TfDir is picked from
+	TerraformVersionsDir string
+	// CheckTimeout bounds each check of the TfDirs not setting their own check_timeout
+	CheckTimeout time.Duration
+	// Timeout bounds the whole run, 0 for none
+	Timeout time.Duration
+	// AllowRepoPlugins loads the plugins declared in the .tf-checker files, which run commands of the
+	// checked repository. They are only run when named explicitly
+	AllowRepoPlugins bool
+}
+
+func (o Options) Validate() error {
+	if o.FailOn != "" && !utils.StrInSlice(Severities(), o.FailOn) {
+		return errors.SeverityNotValidError(fmt.Sprintf("severity %s is not one of %s", o.FailOn, strings.Join(Severities(), ", ")))
+	}
+	return o.Output.Validate()
+}
+
+// StartLocal runs the checks on every TfDir under dir and returns the exit code.
+func StartLocal(dir string, checkTypes filter.TfCheckTypeFilter, opts Options) int {
 	tfRepos := terraform.FindAllTfDir(dir)
 	if len(tfRepos) == 0 {
 		log.Error().Msg(fmt.Sprintf("Could not find any terraform folder under %s", dir))
-		return
+		return ExitNothingFound
+	}
+
+	execConfig := terraform.ExecConfig{ProviderInstallation: opts.ProviderInstallation, VersionsDir: opts.TerraformVersionsDir}
+	if err := execConfig.Setup(); err != nil {
+		log.Error().Err(err).Msg("Error setting up terraform")
+		return ExitError
+	}
+
+	registry := terraform.DefaultRegistry()
+	registry.SetExecConfig(execConfig)
+	tfCheckTypes := checkTypes.TfCheckTypes
+	if len(tfCheckTypes) == 0 {
+		// The plugins of .tf-checker files only run when named with --plugin
+		tfCheckTypes = registry.DefaultNames()
+	}
+
+	pluginManager := plugin.NewManager()
+	defer pluginManager.Kill()
+
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	checks, ok := executeChecks(ctx, dir, tfRepos, registry, tfCheckTypes, opts, pluginManager)
+	if len(checks) == 0 {
+		log.Error().Msg(fmt.Sprintf("No check executed under %s", dir))
+		return ExitNothingFound
+	}
+
+	if opts.Fix {
+		fixed, fixOk := applyFixes(ctx, tfRepos, registry, checks, opts.AllowRepoPlugins, pluginManager)
+		rechecks, recheckOk := executeChecks(ctx, dir, fixed, registry, tfCheckTypes, opts, pluginManager)
+		checks = replaceDirChecks(checks, fixed, rechecks)
+		ok = ok && fixOk && recheckOk
 	}
 
-	checks := make(map[string][]terraform.TfCheck)
+	if err := WriteOutput(checks, opts.Output); err != nil {
+		log.Error().Err(err).Msg("Error writing output")
+		ok = false
+	}
+
+	switch {
+	case !ok:
+		return ExitError
+	case len(FailingChecks(checks, opts.FailOn)) > 0:
+		return ExitChecksFailed
+	default:
+		return ExitOK
+	}
+}
 
-	for _, tfDir := range tfRepos {
+// executeChecks runs the checks of the enabled TfDirs, sorted by dir. ok is false
+// if some checks could not be created. Each check is bounded by the check timeout of its TfDir,
+// or the one of opts if it has none.
+func executeChecks(ctx context.Context, dir string, tfDirs []*terraform.TfDir, registry *terraform.Registry, tfCheckTypes []string, opts Options, pluginManager *plugin.Manager) (checks []terraform.TfCheck, ok bool) {
+	// SYNCHRONIZATION
+	// Wait group for waiting all tasks to be done in the end
+	var tasksDone sync.WaitGroup
+	// Chan allowing to run only n goroutines at the same time
+	currentlyRunning := make(chan int, opts.Parallelism)
+	var checksLock sync.Mutex
+	ok = true
+
+	// Checks are only known once the plugins of every dir are registered
+	unknownChecks := tfCheckTypes
+
+	for _, tfDir := range tfDirs {
 		tfDir := tfDir
 
 		// If tfDir is not enabled, continue
@@ -37,52 +139,75 @@ func StartLocal(dir string, parallelism uint, checkTypes filter.TfCheckTypeFilte
 			continue
 		}
 
+		dirRegistry := terraform.DirRegistry(registry, tfDir, opts.AllowRepoPlugins, pluginManager)
+		unknownChecks = dirRegistry.Unknown(unknownChecks)
+
 		currentlyRunning <- 1 // queue current task
 		tasksDone.Add(1)
 		go func() {
 			defer tasksDone.Done()
 
 			relDir := strings.TrimPrefix(strings.ReplaceAll(tfDir.Path(), dir, ""), "/")
-			for _, check := range terraform.GetTfChecks(tfDir.Path(), relDir, checkTypes.TfCheckTypes) {
-				check.Run()
-				if _, ok := checks[check.Dir()]; !ok {
-					checks[check.Dir()] = []terraform.TfCheck{}
-				}
-				checks[check.Dir()] = append(checks[check.Dir()], check)
+			tfChecks, err := dirRegistry.GetTfChecks(tfDir.Path(), relDir, tfCheckTypes)
+			timeout := opts.CheckTimeout
+			if tfDir.CheckTimeout() > 0 {
+				timeout = tfDir.CheckTimeout()
 			}
+			for _, check := range tfChecks {
+				terraform.RunCheck(ctx, check, timeout)
+			}
+			checksLock.Lock()
+			if err != nil {
+				log.Error().Err(err).Msgf("Error getting checks of tfDir %s", tfDir.Path())
+				ok = false
+			}
+			checks = append(checks, tfChecks...)
+			checksLock.Unlock()
 			<-currentlyRunning // free up space for next one
 		}()
 	}
 	tasksDone.Wait()
 	close(currentlyRunning)
 
-	renderOutput(checks)
+	for _, name := range unknownChecks {
+		if !opts.AllowRepoPlugins && utils.StrInSlice(terraform.DirPluginNames(tfDirs), name) {
+			log.Error().Msgf("Check %s is a plugin of a .tf-checker file, run with --allow-repo-plugins to load it", name)
+		} else {
+			log.Error().Msgf("Unknown check %s", name)
+		}
+		ok = false
+	}
+
+	// Checks of a dir stay in execution order
+	sort.SliceStable(checks, func(i, j int) bool { return checks[i].Dir() < checks[j].Dir() })
+	return checks, ok
 }
 
-func renderOutput(checks map[string][]terraform.TfCheck) {
+func renderText(w io.Writer, checks []terraform.TfCheck) {
 	okSuffix := " ✅"
 	notOkSuffix := " ❌"
+	blue := color.New(color.FgBlue)
 
-	for dir, checks := range checks {
-		dirLine := fmt.Sprintf("--- %s", dir)
-		color.Blue(strings.Repeat("-", len(dirLine)))
-		color.Blue(dirLine)
-		for _, check := range checks {
-			checkName := cases.Title(language.Und, cases.NoLower).String(check.Name())
-			checkTitlePrefix := "\n-- "
-			checkTitle := strings.Repeat("-", len(checkTitlePrefix)+len(checkName)+len(okSuffix))
-			checkTitle += checkTitlePrefix
-
-			if check.IsOK() {
-				checkTitle += color.GreenString(checkName) + okSuffix
-			} else {
-				checkTitle += color.RedString(checkName) + notOkSuffix
-			}
-			fmt.Printf("\n%s\n", checkTitle)
+	for i, check := range checks {
+		if i == 0 || checks[i-1].Dir() != check.Dir() {
+			dirLine := fmt.Sprintf("--- %s", check.Dir())
+			blue.Fprintln(w, strings.Repeat("-", len(dirLine)))
+			blue.Fprintln(w, dirLine)
+		}
+		checkName := cases.Title(language.Und, cases.NoLower).String(check.Name())
+		checkTitlePrefix := "\n-- "
+		checkTitle := strings.Repeat("-", len(checkTitlePrefix)+len(checkName)+len(okSuffix))
+		checkTitle += checkTitlePrefix
 
-			if out := check.Output(); out != "" {
-				fmt.Printf("\n%s", out)
-			}
+		if check.IsOK() {
+			checkTitle += color.GreenString(checkName) + okSuffix
+		} else {
+			checkTitle += color.RedString(checkName) + notOkSuffix
+		}
+		fmt.Fprintf(w, "\n%s\n", checkTitle)
+
+		if out := check.Output(); out != "" {
+			fmt.Fprintf(w, "\n%s", out)
 		}
 	}
 }
@@ -0,0 +1,121 @@
+package local_test
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/terraform-tools/terraform-checker/pkg/filter"
+	"github.com/terraform-tools/terraform-checker/pkg/local"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+func TestStartLocal(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		mainTf   string
+		fix      bool
+		exitCode int
+	}{
+		{
+			name:     "ok",
+			mainTf:   "locals {\n  a = 1\n}\n",
+			exitCode: local.ExitOK,
+		}, {
+			name:     "failed",
+			mainTf:   "locals {\n  a    = 1\n}\n",
+			exitCode: local.ExitChecksFailed,
+		}, {
+			name:     "fixed",
+			mainTf:   "locals {\n  a    = 1\n}\n",
+			fix:      true,
+			exitCode: local.ExitOK,
+		}, {
+			name:     "nothing found",
+			exitCode: local.ExitNothingFound,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			if tc.mainTf != "" {
+				if err := os.WriteFile(path.Join(dir, "main.tf"), []byte(tc.mainTf), 0o600); err != nil {
+					t.Fatalf("Error writing fixture %v", err)
+				}
+			}
+
+			opts := local.Options{
+				Parallelism: 1,
+				Output:      local.Output{Format: local.FormatJSON, File: path.Join(t.TempDir(), "output.json")},
+				Fix:         tc.fix,
+			}
+			checkTypes := filter.TfCheckTypeFilter{TfCheckTypes: []string{terraform.Fmt}}
+			if exitCode := local.StartLocal(dir, checkTypes, opts); exitCode != tc.exitCode {
+				t.Errorf("Expected exit code %v, got %v", tc.exitCode, exitCode)
+			}
+		})
+	}
+}
+
+func TestStartLocalRepoPlugins(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		allowRepoPlugins bool
+		checkTypes       []string
+		exitCode         int
+		ran              bool
+	}{
+		{
+			name:       "not allowed",
+			checkTypes: []string{terraform.Fmt, "repo"},
+			exitCode:   local.ExitError,
+		}, {
+			name:             "allowed",
+			allowRepoPlugins: true,
+			checkTypes:       []string{terraform.Fmt, "repo"},
+			exitCode:         local.ExitOK,
+			ran:              true,
+		}, {
+			name:             "allowed but not named",
+			allowRepoPlugins: true,
+			exitCode:         local.ExitOK,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			marker := path.Join(t.TempDir(), "ran")
+			script := path.Join(t.TempDir(), "check.sh")
+			if err := os.WriteFile(script, []byte("#!/bin/sh\nread -r request\ntouch "+marker+"\necho '{\"ok\": true}'\n"), 0o700); err != nil { //nolint:gosec
+				t.Fatalf("Error writing fixture %v", err)
+			}
+			if err := os.WriteFile(path.Join(dir, "main.tf"), []byte("terraform {\n  required_version = \">= 1.0\"\n}\n"), 0o600); err != nil {
+				t.Fatalf("Error writing fixture %v", err)
+			}
+			if err := os.WriteFile(path.Join(dir, ".tf-checker"), []byte("plugins:\n  - name: repo\n    command: "+script+"\n"), 0o600); err != nil {
+				t.Fatalf("Error writing fixture %v", err)
+			}
+
+			opts := local.Options{
+				Parallelism:      1,
+				Output:           local.Output{Format: local.FormatJSON, File: path.Join(t.TempDir(), "output.json")},
+				AllowRepoPlugins: tc.allowRepoPlugins,
+			}
+			checkTypes := filter.TfCheckTypeFilter{TfCheckTypes: tc.checkTypes}
+			if exitCode := local.StartLocal(dir, checkTypes, opts); exitCode != tc.exitCode {
+				t.Errorf("Expected exit code %v, got %v", tc.exitCode, exitCode)
+			}
+			if _, err := os.Stat(marker); (err == nil) != tc.ran {
+				t.Errorf("Expected the repo plugin to run only when allowed and named, ran: %v", err == nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package local
+
+import (
+	"strings"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+// Severities accepted as threshold to fail the local mode.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityNotice  = "notice"
+)
+
+// Severities returns the supported severities, from the most to the least severe.
+func Severities() []string {
+	return []string{SeverityError, SeverityWarning, SeverityNotice}
+}
+
+// Ranks of the annotation levels, the most severe being the highest.
+const (
+	rankNone = iota
+	rankNotice
+	rankWarning
+	rankFailure
+)
+
+// annotationLevelRank ranks github annotation levels.
+func annotationLevelRank(level string) int {
+	switch level {
+	case strings.ToLower(string(githubv4.CheckAnnotationLevelFailure)):
+		return rankFailure
+	case strings.ToLower(string(githubv4.CheckAnnotationLevelWarning)):
+		return rankWarning
+	case strings.ToLower(string(githubv4.CheckAnnotationLevelNotice)):
+		return rankNotice
+	default:
+		return rankNone
+	}
+}
+
+// severityRank ranks a severity like the annotation level it matches.
+func severityRank(severity string) int {
+	switch severity {
+	case SeverityError:
+		return rankFailure
+	case SeverityWarning:
+		return rankWarning
+	default:
+		return rankNotice
+	}
+}
+
+// checkRank ranks a failing check by its most severe annotation. Checks failing
+// without annotations (fmt, init errors...) rank as failures.
+func checkRank(check terraform.TfCheck) int {
+	annotations := check.Annotations()
+	if len(annotations) == 0 {
+		return rankFailure
+	}
+
+	rank := rankNone
+	for _, annotation := range annotations {
+		if r := annotationLevelRank(annotation.GetAnnotationLevel()); r > rank {
+			rank = r
+		}
+	}
+	return rank
+}
+
+// FailingChecks returns the failing checks whose severity reaches failOn.
+// An empty failOn makes every failing check count.
+func FailingChecks(checks []terraform.TfCheck, failOn string) []terraform.TfCheck {
+	failing := []terraform.TfCheck{}
+	for _, check := range checks {
+		if !check.IsOK() && checkRank(check) >= severityRank(failOn) {
+			failing = append(failing, check)
+		}
+	}
+	return failing
+}
@@ -0,0 +1,43 @@
+package local_test
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/terraform-tools/terraform-checker/pkg/local"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+func TestFailingChecks(t *testing.T) {
+	t.Parallel()
+	checks := []terraform.TfCheck{
+		// Failing without annotations
+		&fakeCheck{name: "fmt", relDir: "a"},
+		&fakeCheck{name: "tflint", relDir: "a", annotations: []*github.CheckRunAnnotation{
+			{AnnotationLevel: github.String("notice")},
+			{AnnotationLevel: github.String("warning")},
+		}},
+		&fakeCheck{name: "tflint", relDir: "b", annotations: []*github.CheckRunAnnotation{{AnnotationLevel: github.String("notice")}}},
+		// OK checks never fail
+		&fakeCheck{name: "validate", relDir: "a", ok: true, annotations: []*github.CheckRunAnnotation{{AnnotationLevel: github.String("failure")}}},
+	}
+
+	testCases := []struct {
+		failOn  string
+		failing int
+	}{
+		{failOn: local.SeverityError, failing: 1},
+		{failOn: local.SeverityWarning, failing: 2},
+		{failOn: local.SeverityNotice, failing: 3},
+		{failOn: "", failing: 3},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.failOn, func(t *testing.T) {
+			t.Parallel()
+			if failing := local.FailingChecks(checks, tc.failOn); len(failing) != tc.failing {
+				t.Errorf("Expected %v failing checks on %v, got %v", tc.failing, tc.failOn, len(failing))
+			}
+		})
+	}
+}
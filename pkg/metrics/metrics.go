@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "tf_checker"
+
+//nolint:gochecknoglobals // metrics are registered once in the default registry
+var (
+	webhooksReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhooks_received_total",
+		Help:      "Webhooks received, by event type and action.",
+	}, []string{"event_type", "action"})
+
+	eventsDiscarded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_discarded_total",
+		Help:      "Events discarded without running checks, by event type and reason.",
+	}, []string{"event_type", "reason"})
+
+	cloneDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "clone_duration_seconds",
+		Help:      "Duration of the clones of the checked repositories.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10), //nolint:gomnd // from 0.5s to ~4min
+	})
+
+	checkDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "check_duration_seconds",
+		Help:      "Duration of the checks of a dir, by check type and outcome.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14), //nolint:gomnd // from 0.1s to ~14min
+	}, []string{"check", "outcome"})
+
+	terraformInitFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "terraform_init_failures_total",
+		Help:      "Failed terraform init runs.",
+	})
+
+	githubAPIErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "github_api_errors_total",
+		Help:      "Errors returned by the GitHub API, by operation.",
+	}, []string{"operation"})
+)
+
+// Reasons of the discarded events.
+const (
+	ReasonInvalidPayload = "invalid_payload"
+	ReasonOtherApp       = "other_app"
+	ReasonAction         = "action"
+	ReasonNoPullRequest  = "no_pull_request"
+	ReasonRepoWhitelist  = "repo_whitelist"
+	ReasonRepoTopic      = "repo_topic"
+	ReasonClientError    = "client_error"
+)
+
+// WebhookReceived counts a webhook, whose action is read from its payload.
+func WebhookReceived(eventType string, payload []byte) {
+	var event struct {
+		Action string `json:"action"`
+	}
+	// Events without action, e.g. push, are counted with an empty one
+	_ = json.Unmarshal(payload, &event)
+	webhooksReceived.WithLabelValues(eventType, event.Action).Inc()
+}
+
+func EventDiscarded(eventType, reason string) {
+	eventsDiscarded.WithLabelValues(eventType, reason).Inc()
+}
+
+func ObserveClone(start time.Time) {
+	cloneDuration.Observe(time.Since(start).Seconds())
+}
+
+// ObserveCheck records the duration of a check, outcome being success or the conclusion of its failure.
+func ObserveCheck(check, outcome string, start time.Time) {
+	checkDuration.WithLabelValues(check, strings.ToLower(outcome)).Observe(time.Since(start).Seconds())
+}
+
+func TerraformInitFailed() {
+	terraformInitFailures.Inc()
+}
+
+func GithubAPIError(operation string) {
+	githubAPIErrors.WithLabelValues(operation).Inc()
+}
+
+// RegisterQueue registers the gauges of the events queued and running, read from the given funcs.
+func RegisterQueue(queued, running func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_events_queued",
+		Help:      "Events waiting in the queue.",
+	}, func() float64 { return float64(queued()) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_events_running",
+		Help:      "Events being handled by the queue workers.",
+	}, func() float64 { return float64(running()) })
+}
@@ -0,0 +1,27 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/terraform-tools/terraform-checker/pkg/metrics"
+)
+
+func TestWebhookReceived(t *testing.T) {
+	t.Parallel()
+	metrics.WebhookReceived("check_suite", []byte(`{"action": "requested", "check_suite": {}}`))
+	metrics.WebhookReceived("check_suite", []byte(`{"action": "requested"}`))
+	metrics.WebhookReceived("pull_request", []byte(`not json`))
+
+	expected := `
+# HELP tf_checker_webhooks_received_total Webhooks received, by event type and action.
+# TYPE tf_checker_webhooks_received_total counter
+tf_checker_webhooks_received_total{action="",event_type="pull_request"} 1
+tf_checker_webhooks_received_total{action="requested",event_type="check_suite"} 2
+`
+	if err := testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected), "tf_checker_webhooks_received_total"); err != nil {
+		t.Errorf("Unexpected metrics: %v", err)
+	}
+}
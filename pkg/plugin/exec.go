@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/terraform-tools/terraform-checker/pkg/utils"
+)
+
+// Response is read as JSON from the stdout of exec plugins, which receive the Request
+// as JSON on their stdin.
+// FixCommand is an optional command, executed inside the checked dir, fixing the issues found.
+type Response struct {
+	OK          bool         `json:"ok"`
+	Output      string       `json:"output"`
+	Annotations []Annotation `json:"annotations"`
+	FixCommand  []string     `json:"fix_command"` //nolint:tagliatelle
+}
+
+type execCheck struct {
+	config Config
+}
+
+func newExecCheck(c Config) *execCheck {
+	return &execCheck{config: c}
+}
+
+func (e *execCheck) Name() string {
+	return e.config.Name
+}
+
+func (e *execCheck) Run(req Request) (Result, error) {
+	return e.RunContext(context.Background(), req)
+}
+
+func (e *execCheck) RunContext(ctx context.Context, req Request) (Result, error) {
+	resp, err := e.run(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixAction *FixAction
+	if len(resp.FixCommand) > 0 {
+		fixAction = &FixAction{
+			Label:       "Trigger fix",
+			Description: fmt.Sprintf("Add a %s fix commit", e.config.Name),
+		}
+	}
+	return NewResult(resp.OK, resp.Output, resp.Annotations, fixAction), nil
+}
+
+func (e *execCheck) Fix(req Request) (string, error) {
+	return e.FixContext(context.Background(), req)
+}
+
+func (e *execCheck) FixContext(ctx context.Context, req Request) (string, error) {
+	resp, err := e.run(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if resp.OK || len(resp.FixCommand) == 0 {
+		return "", nil
+	}
+
+	cmd := utils.CommandContext(ctx, resp.FixCommand[0], resp.FixCommand[1:]...) // #nosec
+	cmd.Dir = req.Dir
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// run executes the plugin for a dir and decodes its response.
+func (e *execCheck) run(ctx context.Context, req Request) (*Response, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := utils.CommandContext(ctx, e.config.Command, e.config.Args...) // #nosec
+	cmd.Dir = req.Dir
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("plugin %s failed: %w: %s", e.config.Name, runErr, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("plugin %s returned an invalid response: %w", e.config.Name, err)
+	}
+	return &resp, nil
+}
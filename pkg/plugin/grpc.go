@@ -0,0 +1,252 @@
+package plugin
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	checkPluginName      = "check"
+	handshakeCookieKey   = "TF_CHECKER_PLUGIN"
+	handshakeCookieValue = "terraform-checker"
+)
+
+// Handshake is shared by terraform-checker and its long-lived plugins.
+func Handshake() goplugin.HandshakeConfig {
+	return goplugin.HandshakeConfig{
+		ProtocolVersion:  1,
+		MagicCookieKey:   handshakeCookieKey,
+		MagicCookieValue: handshakeCookieValue,
+	}
+}
+
+// PluginMap returns the plugins served by a long-lived plugin binary, impl is nil on the client side.
+func PluginMap(impl Check) map[string]goplugin.Plugin {
+	return map[string]goplugin.Plugin{
+		checkPluginName: &CheckGRPCPlugin{Impl: impl},
+	}
+}
+
+// Serve must be called by the main function of long-lived plugin binaries.
+func Serve(impl Check) {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: Handshake(),
+		Plugins:         PluginMap(impl),
+		GRPCServer:      goplugin.DefaultGRPCServer,
+	})
+}
+
+// CheckGRPCPlugin is the go-plugin implementation of Check over gRPC.
+type CheckGRPCPlugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+	Impl Check
+}
+
+func (p *CheckGRPCPlugin) GRPCServer(_ *goplugin.GRPCBroker, s *grpc.Server) error {
+	proto.RegisterCheckServer(s, &grpcServer{
+		impl:    p.Impl,
+		results: make(map[string]Result),
+	})
+	return nil
+}
+
+func (p *CheckGRPCPlugin) GRPCClient(_ context.Context, _ *goplugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &grpcClient{client: proto.NewCheckClient(c)}, nil
+}
+
+// grpcServer runs in the plugin process and keeps results until they are released.
+type grpcServer struct {
+	proto.UnimplementedCheckServer
+	impl Check
+
+	mu      sync.Mutex
+	lastID  int
+	results map[string]Result
+}
+
+func (s *grpcServer) Name(_ context.Context, _ *proto.NameRequest) (*proto.NameResponse, error) {
+	return &proto.NameResponse{Name: s.impl.Name()}, nil
+}
+
+// Run gives ctx to the plugins implementing ContextCheck, it is cancelled when the check or the
+// event times out or is cancelled in terraform-checker.
+func (s *grpcServer) Run(ctx context.Context, req *proto.RunRequest) (*proto.RunResponse, error) {
+	var res Result
+	var err error
+	if c, ok := s.impl.(ContextCheck); ok {
+		res, err = c.RunContext(ctx, requestFromProto(req))
+	} else {
+		res, err = s.impl.Run(requestFromProto(req))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	id := strconv.Itoa(s.lastID)
+	s.results[id] = res
+
+	return &proto.RunResponse{ResultId: id, Ok: res.OK()}, nil
+}
+
+func (s *grpcServer) result(id string) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, ok := s.results[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown result %s", id)
+	}
+	return res, nil
+}
+
+func (s *grpcServer) Output(_ context.Context, req *proto.ResultRequest) (*proto.OutputResponse, error) {
+	res, err := s.result(req.GetResultId())
+	if err != nil {
+		return nil, err
+	}
+	return &proto.OutputResponse{Output: res.Output()}, nil
+}
+
+func (s *grpcServer) Annotations(_ context.Context, req *proto.ResultRequest) (*proto.AnnotationsResponse, error) {
+	res, err := s.result(req.GetResultId())
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := make([]*proto.Annotation, 0, len(res.Annotations()))
+	for _, a := range res.Annotations() {
+		annotations = append(annotations, &proto.Annotation{
+			Path:      a.Path,
+			StartLine: int32(a.StartLine),
+			EndLine:   int32(a.EndLine),
+			Severity:  a.Severity,
+			Title:     a.Title,
+			Message:   a.Message,
+		})
+	}
+	return &proto.AnnotationsResponse{Annotations: annotations}, nil
+}
+
+func (s *grpcServer) FixAction(_ context.Context, req *proto.ResultRequest) (*proto.FixActionResponse, error) {
+	res, err := s.result(req.GetResultId())
+	if err != nil {
+		return nil, err
+	}
+	fixAction := res.FixAction()
+	if fixAction == nil {
+		return &proto.FixActionResponse{}, nil
+	}
+	return &proto.FixActionResponse{Available: true, Label: fixAction.Label, Description: fixAction.Description}, nil
+}
+
+func (s *grpcServer) Release(_ context.Context, req *proto.ResultRequest) (*proto.ReleaseResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.results, req.GetResultId())
+	return &proto.ReleaseResponse{}, nil
+}
+
+func (s *grpcServer) Fix(ctx context.Context, req *proto.RunRequest) (*proto.FixResponse, error) {
+	var out string
+	var err error
+	if c, ok := s.impl.(ContextCheck); ok {
+		out, err = c.FixContext(ctx, requestFromProto(req))
+	} else {
+		out, err = s.impl.Fix(requestFromProto(req))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &proto.FixResponse{Output: out}, nil
+}
+
+// grpcClient implements Check in terraform-checker, on top of the gRPC connection to the plugin.
+type grpcClient struct {
+	client proto.CheckClient
+}
+
+func (c *grpcClient) Name() string {
+	resp, err := c.client.Name(context.Background(), &proto.NameRequest{})
+	if err != nil {
+		return ""
+	}
+	return resp.GetName()
+}
+
+func (c *grpcClient) Run(req Request) (Result, error) {
+	return c.RunContext(context.Background(), req)
+}
+
+// RunContext fetches the whole result right away, so that it can be released from the plugin.
+func (c *grpcClient) RunContext(ctx context.Context, req Request) (Result, error) {
+	runResp, err := c.client.Run(ctx, requestToProto(req))
+	if err != nil {
+		return nil, err
+	}
+	resultReq := &proto.ResultRequest{ResultId: runResp.GetResultId()}
+	defer func() {
+		// The result is released even if ctx is done
+		_, _ = c.client.Release(context.Background(), resultReq)
+	}()
+
+	outputResp, err := c.client.Output(ctx, resultReq)
+	if err != nil {
+		return nil, err
+	}
+	annotationsResp, err := c.client.Annotations(ctx, resultReq)
+	if err != nil {
+		return nil, err
+	}
+	fixActionResp, err := c.client.FixAction(ctx, resultReq)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := make([]Annotation, 0, len(annotationsResp.GetAnnotations()))
+	for _, a := range annotationsResp.GetAnnotations() {
+		annotations = append(annotations, Annotation{
+			Path:      a.GetPath(),
+			StartLine: int(a.GetStartLine()),
+			EndLine:   int(a.GetEndLine()),
+			Severity:  a.GetSeverity(),
+			Title:     a.GetTitle(),
+			Message:   a.GetMessage(),
+		})
+	}
+
+	var fixAction *FixAction
+	if fixActionResp.GetAvailable() {
+		fixAction = &FixAction{Label: fixActionResp.GetLabel(), Description: fixActionResp.GetDescription()}
+	}
+
+	return NewResult(runResp.GetOk(), outputResp.GetOutput(), annotations, fixAction), nil
+}
+
+func (c *grpcClient) Fix(req Request) (string, error) {
+	return c.FixContext(context.Background(), req)
+}
+
+func (c *grpcClient) FixContext(ctx context.Context, req Request) (string, error) {
+	resp, err := c.client.Fix(ctx, requestToProto(req))
+	if err != nil {
+		return "", err
+	}
+	return resp.GetOutput(), nil
+}
+
+func requestToProto(req Request) *proto.RunRequest {
+	return &proto.RunRequest{Dir: req.Dir, RelDir: req.RelDir, ChangedFiles: req.ChangedFiles}
+}
+
+func requestFromProto(req *proto.RunRequest) Request {
+	return Request{Dir: req.GetDir(), RelDir: req.GetRelDir(), ChangedFiles: req.GetChangedFiles()}
+}
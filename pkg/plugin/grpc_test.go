@@ -0,0 +1,124 @@
+package plugin_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin"
+)
+
+type testCheck struct{}
+
+func (c *testCheck) Name() string {
+	return "test"
+}
+
+func (c *testCheck) Run(req plugin.Request) (plugin.Result, error) {
+	return plugin.NewResult(
+		false,
+		"failed on "+req.RelDir,
+		[]plugin.Annotation{{Path: "main.tf", StartLine: 2, Severity: "error", Message: "wrong"}},
+		&plugin.FixAction{Label: "Fix it", Description: "Fix it all"},
+	), nil
+}
+
+func (c *testCheck) Fix(req plugin.Request) (string, error) {
+	return "fixed " + req.RelDir, nil
+}
+
+func TestCheckGRPCPlugin(t *testing.T) {
+	t.Parallel()
+
+	client, server := goplugin.TestPluginGRPCConn(t, plugin.PluginMap(&testCheck{}))
+	defer client.Close()
+	defer server.Stop()
+
+	raw, err := client.Dispense("check")
+	if err != nil {
+		t.Fatalf("Dispense failed: %v", err)
+	}
+	check, ok := raw.(plugin.Check)
+	if !ok {
+		t.Fatalf("Dispensed plugin does not implement Check")
+	}
+
+	if name := check.Name(); name != "test" {
+		t.Errorf("Expected name test, got %v", name)
+	}
+
+	res, err := check.Run(plugin.Request{Dir: "/tmp/dir", RelDir: "dir"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.OK() || res.Output() != "failed on dir" {
+		t.Errorf("Unexpected result ok %v, output %v", res.OK(), res.Output())
+	}
+	if a := res.Annotations(); len(a) != 1 || a[0].Path != "main.tf" || a[0].StartLine != 2 || a[0].Severity != "error" {
+		t.Errorf("Unexpected annotations %v", a)
+	}
+	if f := res.FixAction(); f == nil || f.Label != "Fix it" {
+		t.Errorf("Unexpected fix action %v", f)
+	}
+
+	out, err := check.Fix(plugin.Request{Dir: "/tmp/dir", RelDir: "dir"})
+	if err != nil || out != "fixed dir" {
+		t.Errorf("Unexpected fix output %v, err %v", out, err)
+	}
+}
+
+// contextCheck blocks until the context of its run or fix is done.
+type contextCheck struct {
+	testCheck
+}
+
+func (c *contextCheck) RunContext(ctx context.Context, _ plugin.Request) (plugin.Result, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (c *contextCheck) FixContext(ctx context.Context, _ plugin.Request) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestCheckGRPCPluginContext(t *testing.T) {
+	t.Parallel()
+
+	client, server := goplugin.TestPluginGRPCConn(t, plugin.PluginMap(&contextCheck{}))
+	defer client.Close()
+	defer server.Stop()
+
+	raw, err := client.Dispense("check")
+	if err != nil {
+		t.Fatalf("Dispense failed: %v", err)
+	}
+	check, ok := raw.(plugin.ContextCheck)
+	if !ok {
+		t.Fatalf("Dispensed plugin does not implement ContextCheck")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 2)
+	go func() {
+		_, err := check.RunContext(ctx, plugin.Request{Dir: "/tmp/dir", RelDir: "dir"})
+		done <- err
+	}()
+	go func() {
+		_, err := check.FixContext(ctx, plugin.Request{Dir: "/tmp/dir", RelDir: "dir"})
+		done <- err
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Errorf("Expected an error once the context is done")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("The plugin did not get the context of the check")
+		}
+	}
+}
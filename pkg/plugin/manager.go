@@ -0,0 +1,88 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/rs/zerolog/log"
+)
+
+// Manager returns the checks of plugins, and keeps the long-lived ones running
+// so that they are shared across check runs.
+type Manager struct {
+	mu      sync.Mutex
+	clients map[string]*goplugin.Client
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		clients: make(map[string]*goplugin.Client),
+	}
+}
+
+// Start starts the long-lived plugins right away instead of on their first check.
+func (m *Manager) Start(plugins []Config) error {
+	for _, p := range plugins {
+		if _, err := m.Check(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check returns the check of a plugin, long-lived plugins are started if they are not running.
+func (m *Manager) Check(c Config) (Check, error) {
+	if c.Protocol != ProtocolGRPC {
+		return newExecCheck(c), nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := strings.Join(append([]string{c.Command}, c.Args...), " ")
+	client, ok := m.clients[key]
+	if !ok || client.Exited() {
+		log.Info().Msgf("Starting plugin %s", c.Name)
+		client = goplugin.NewClient(&goplugin.ClientConfig{
+			HandshakeConfig:  Handshake(),
+			Plugins:          PluginMap(nil),
+			Cmd:              exec.Command(c.Command, c.Args...), // #nosec
+			AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+			Logger: hclog.New(&hclog.LoggerOptions{
+				Name:   "plugin." + c.Name,
+				Output: os.Stderr,
+				Level:  hclog.Warn,
+			}),
+		})
+		m.clients[key] = client
+	}
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s could not be started: %w", c.Name, err)
+	}
+	raw, err := rpcClient.Dispense(checkPluginName)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s could not be dispensed: %w", c.Name, err)
+	}
+	check, ok := raw.(Check)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s does not implement a check", c.Name)
+	}
+	return check, nil
+}
+
+// Kill stops all of the long-lived plugins.
+func (m *Manager) Kill() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, client := range m.clients {
+		client.Kill()
+		delete(m.clients, key)
+	}
+}
@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/terraform-tools/terraform-checker/pkg/errors"
+	"github.com/terraform-tools/terraform-checker/pkg/utils"
+)
+
+const (
+	// ProtocolExec plugins are executed once per checked dir.
+	ProtocolExec = "exec"
+	// ProtocolGRPC plugins are started once and kept running, see Serve.
+	ProtocolGRPC = "grpc"
+
+	maxNameLength = 20
+)
+
+// Config declares an external check, either in the server config or in a .tf-checker file.
+type Config struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description" json:"description"`
+	Command     string   `yaml:"command" json:"command"`
+	Args        []string `yaml:"args" json:"args"`
+	Protocol    string   `yaml:"protocol" json:"protocol"`
+	DependsOn   []string `yaml:"depends_on" json:"depends_on"` //nolint:tagliatelle
+}
+
+// Request describes the dir a plugin is run on. ChangedFiles are the files of the dir
+// changed by the pull request, relative to the dir, empty outside of pull requests.
+type Request struct {
+	Dir          string   `json:"dir"`
+	RelDir       string   `json:"rel_dir"`       //nolint:tagliatelle
+	ChangedFiles []string `json:"changed_files"` //nolint:tagliatelle
+}
+
+// Annotation locates an issue found by a plugin, Path is relative to the checked dir.
+type Annotation struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"` //nolint:tagliatelle
+	EndLine   int    `json:"end_line"`   //nolint:tagliatelle
+	Severity  string `json:"severity"`
+	Title     string `json:"title"`
+	Message   string `json:"message"`
+}
+
+// FixAction describes the fix offered by a plugin for a failing result.
+type FixAction struct {
+	Label       string
+	Description string
+}
+
+// Check is implemented by plugins, it mirrors terraform.TfCheck.
+type Check interface {
+	Name() string
+	Run(req Request) (Result, error)
+	// Fix fixes in place the issues found on a dir, and returns its output.
+	Fix(req Request) (string, error)
+}
+
+// ContextCheck is implemented by the checks returned by the Manager, whose runs and fixes
+// are stopped when ctx is done. Long-lived plugins implementing it get the context of the
+// check, done when the check or the event times out or is cancelled.
+type ContextCheck interface {
+	RunContext(ctx context.Context, req Request) (Result, error)
+	FixContext(ctx context.Context, req Request) (string, error)
+}
+
+// Result is the outcome of a plugin check on a dir.
+type Result interface {
+	OK() bool
+	Output() string
+	Annotations() []Annotation
+	FixAction() *FixAction
+}
+
+type result struct {
+	ok          bool
+	output      string
+	annotations []Annotation
+	fixAction   *FixAction
+}
+
+// NewResult returns a Result holding the given values, fixAction is nil when no fix is available.
+func NewResult(ok bool, output string, annotations []Annotation, fixAction *FixAction) Result {
+	return &result{
+		ok:          ok,
+		output:      output,
+		annotations: annotations,
+		fixAction:   fixAction,
+	}
+}
+
+func (r *result) OK() bool {
+	return r.ok
+}
+
+func (r *result) Output() string {
+	return r.output
+}
+
+func (r *result) Annotations() []Annotation {
+	return r.annotations
+}
+
+func (r *result) FixAction() *FixAction {
+	return r.fixAction
+}
+
+// Find returns the plugin named name, or nil.
+func Find(plugins []Config, name string) *Config {
+	for i := range plugins {
+		if plugins[i].Name == name {
+			return &plugins[i]
+		}
+	}
+	return nil
+}
+
+// Names returns the names of the plugins, without duplicates.
+func Names(plugins []Config) []string {
+	names := []string{}
+	for _, p := range plugins {
+		if !utils.StrInSlice(names, p.Name) {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// Validate checks that a plugin can be used as a check named after it.
+func (c Config) Validate() error {
+	if c.Name == "" || c.Command == "" {
+		return errors.PluginNotValidError("name and command fields are mandatory")
+	}
+	// The name is used as identifier of the check run fix action
+	if len(c.Name) > maxNameLength {
+		return errors.PluginNotValidError(fmt.Sprintf("name %s is longer than %d characters", c.Name, maxNameLength))
+	}
+	if c.Protocol != "" && c.Protocol != ProtocolExec && c.Protocol != ProtocolGRPC {
+		return errors.PluginNotValidError(fmt.Sprintf("protocol must be one of %s / %s", ProtocolExec, ProtocolGRPC))
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: check.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Check_Name_FullMethodName        = "/tfchecker.plugin.v1.Check/Name"
+	Check_Run_FullMethodName         = "/tfchecker.plugin.v1.Check/Run"
+	Check_Output_FullMethodName      = "/tfchecker.plugin.v1.Check/Output"
+	Check_Annotations_FullMethodName = "/tfchecker.plugin.v1.Check/Annotations"
+	Check_FixAction_FullMethodName   = "/tfchecker.plugin.v1.Check/FixAction"
+	Check_Release_FullMethodName     = "/tfchecker.plugin.v1.Check/Release"
+	Check_Fix_FullMethodName         = "/tfchecker.plugin.v1.Check/Fix"
+)
+
+// CheckClient is the client API for Check service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CheckClient interface {
+	Name(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*NameResponse, error)
+	Run(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunResponse, error)
+	Output(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*OutputResponse, error)
+	Annotations(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*AnnotationsResponse, error)
+	FixAction(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*FixActionResponse, error)
+	Release(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*ReleaseResponse, error)
+	Fix(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*FixResponse, error)
+}
+
+type checkClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCheckClient(cc grpc.ClientConnInterface) CheckClient {
+	return &checkClient{cc}
+}
+
+func (c *checkClient) Name(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*NameResponse, error) {
+	out := new(NameResponse)
+	err := c.cc.Invoke(ctx, Check_Name_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) Run(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunResponse, error) {
+	out := new(RunResponse)
+	err := c.cc.Invoke(ctx, Check_Run_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) Output(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*OutputResponse, error) {
+	out := new(OutputResponse)
+	err := c.cc.Invoke(ctx, Check_Output_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) Annotations(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*AnnotationsResponse, error) {
+	out := new(AnnotationsResponse)
+	err := c.cc.Invoke(ctx, Check_Annotations_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) FixAction(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*FixActionResponse, error) {
+	out := new(FixActionResponse)
+	err := c.cc.Invoke(ctx, Check_FixAction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) Release(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*ReleaseResponse, error) {
+	out := new(ReleaseResponse)
+	err := c.cc.Invoke(ctx, Check_Release_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) Fix(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*FixResponse, error) {
+	out := new(FixResponse)
+	err := c.cc.Invoke(ctx, Check_Fix_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CheckServer is the server API for Check service.
+// All implementations must embed UnimplementedCheckServer
+// for forward compatibility
+type CheckServer interface {
+	Name(context.Context, *NameRequest) (*NameResponse, error)
+	Run(context.Context, *RunRequest) (*RunResponse, error)
+	Output(context.Context, *ResultRequest) (*OutputResponse, error)
+	Annotations(context.Context, *ResultRequest) (*AnnotationsResponse, error)
+	FixAction(context.Context, *ResultRequest) (*FixActionResponse, error)
+	Release(context.Context, *ResultRequest) (*ReleaseResponse, error)
+	Fix(context.Context, *RunRequest) (*FixResponse, error)
+	mustEmbedUnimplementedCheckServer()
+}
+
+// UnimplementedCheckServer must be embedded to have forward compatible implementations.
+type UnimplementedCheckServer struct {
+}
+
+func (UnimplementedCheckServer) Name(context.Context, *NameRequest) (*NameResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Name not implemented")
+}
+func (UnimplementedCheckServer) Run(context.Context, *RunRequest) (*RunResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Run not implemented")
+}
+func (UnimplementedCheckServer) Output(context.Context, *ResultRequest) (*OutputResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Output not implemented")
+}
+func (UnimplementedCheckServer) Annotations(context.Context, *ResultRequest) (*AnnotationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Annotations not implemented")
+}
+func (UnimplementedCheckServer) FixAction(context.Context, *ResultRequest) (*FixActionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FixAction not implemented")
+}
+func (UnimplementedCheckServer) Release(context.Context, *ResultRequest) (*ReleaseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Release not implemented")
+}
+func (UnimplementedCheckServer) Fix(context.Context, *RunRequest) (*FixResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Fix not implemented")
+}
+func (UnimplementedCheckServer) mustEmbedUnimplementedCheckServer() {}
+
+// UnsafeCheckServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CheckServer will
+// result in compilation errors.
+type UnsafeCheckServer interface {
+	mustEmbedUnimplementedCheckServer()
+}
+
+func RegisterCheckServer(s grpc.ServiceRegistrar, srv CheckServer) {
+	s.RegisterService(&Check_ServiceDesc, srv)
+}
+
+func _Check_Name_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).Name(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Check_Name_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).Name(ctx, req.(*NameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_Run_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).Run(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Check_Run_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).Run(ctx, req.(*RunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_Output_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).Output(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Check_Output_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).Output(ctx, req.(*ResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_Annotations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).Annotations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Check_Annotations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).Annotations(ctx, req.(*ResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_FixAction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).FixAction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Check_FixAction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).FixAction(ctx, req.(*ResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_Release_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).Release(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Check_Release_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).Release(ctx, req.(*ResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_Fix_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).Fix(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Check_Fix_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).Fix(ctx, req.(*RunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Check_ServiceDesc is the grpc.ServiceDesc for Check service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Check_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tfchecker.plugin.v1.Check",
+	HandlerType: (*CheckServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Name",
+			Handler:    _Check_Name_Handler,
+		},
+		{
+			MethodName: "Run",
+			Handler:    _Check_Run_Handler,
+		},
+		{
+			MethodName: "Output",
+			Handler:    _Check_Output_Handler,
+		},
+		{
+			MethodName: "Annotations",
+			Handler:    _Check_Annotations_Handler,
+		},
+		{
+			MethodName: "FixAction",
+			Handler:    _Check_FixAction_Handler,
+		},
+		{
+			MethodName: "Release",
+			Handler:    _Check_Release_Handler,
+		},
+		{
+			MethodName: "Fix",
+			Handler:    _Check_Fix_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "check.proto",
+}
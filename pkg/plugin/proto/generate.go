@@ -0,0 +1,3 @@
+package proto
+
+//go:generate buf generate --template buf.gen.yaml .
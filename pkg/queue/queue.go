@@ -0,0 +1,284 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/palantir/go-githubapp/githubapp"
+	"github.com/rs/zerolog/log"
+	"github.com/terraform-tools/terraform-checker/pkg/errors"
+	"github.com/terraform-tools/terraform-checker/pkg/metrics"
+)
+
+const (
+	// cancelWaitTimeout bounds the wait for the events cancelled on shutdown to end, it is taken
+	// from the time left before the deadline of the shutdown, at most 1/cancelWaitShare of it
+	cancelWaitTimeout = 10 * time.Second
+	cancelWaitShare   = 4
+)
+
+type job struct {
+	dispatch githubapp.Dispatch
+	record   *record
+}
+
+// InterruptedHandler is an event handler told about the events whose handling was interrupted
+// by a stop of the server, e.g. to conclude the check runs left in progress.
+type InterruptedHandler interface {
+	githubapp.EventHandler
+	HandleInterrupted(ctx context.Context, eventType, deliveryID string, payload []byte) error
+}
+
+// Queue is a githubapp.Scheduler queuing the webhook events to a fixed number of workers, so that
+// GitHub gets its response as soon as the event is queued. Events are refused with
+// githubapp.ErrCapacityExceeded when the queue is full.
+// With a Store, the events are persisted until they are handled and can be resumed on startup.
+type Queue struct {
+	jobs    chan job
+	workers int
+	running int64
+	store   *Store
+	// ctx is the context of the handled events, cancelled if they don't end within the grace
+	// period of the shutdown
+	ctx      context.Context //nolint:containedctx // shared by the workers
+	cancel   context.CancelCauseFunc
+	stopping chan struct{}
+	stopOnce sync.Once
+	stopped  sync.WaitGroup
+}
+
+// Stats of a Queue.
+type Stats struct {
+	Queued   int `json:"queued"`
+	Running  int `json:"running"`
+	Size     int `json:"size"`
+	Workers  int `json:"workers"`
+	Capacity int `json:"capacity"`
+}
+
+// New returns a Queue holding up to size events, handled by workers goroutines. Both must be
+// positive, their defaults are the ones of the config. store may be nil if the events don't
+// need to be persisted.
+func New(size, workers int, store *Store) (*Queue, error) {
+	if size <= 0 || workers <= 0 {
+		return nil, errors.ConfigNotValidError(fmt.Sprintf("queue size %d and workers %d must be positive", size, workers))
+	}
+	q := &Queue{
+		jobs:     make(chan job, size),
+		workers:  workers,
+		store:    store,
+		stopping: make(chan struct{}),
+	}
+	q.ctx, q.cancel = context.WithCancelCause(githubapp.InitializeResponder(context.Background()))
+	for i := 0; i < workers; i++ {
+		q.stopped.Add(1)
+		go q.work()
+	}
+	return q, nil
+}
+
+// Schedule queues an event, it returns githubapp.ErrCapacityExceeded if the queue is full or
+// shutting down.
+func (q *Queue) Schedule(_ context.Context, d githubapp.Dispatch) error {
+	metrics.WebhookReceived(d.EventType, d.Payload)
+	if q.isStopping() {
+		log.Warn().Msgf("Refusing %s event %s, shutting down", d.EventType, d.DeliveryID)
+		return githubapp.ErrCapacityExceeded
+	}
+
+	r := &record{EventType: d.EventType, DeliveryID: d.DeliveryID, Payload: d.Payload, QueuedAt: time.Now()}
+	if err := q.store.add(r); err != nil {
+		return err
+	}
+
+	select {
+	case q.jobs <- job{dispatch: d, record: r}:
+		log.Debug().Msgf("Queued %s event %s, %d events queued", d.EventType, d.DeliveryID, len(q.jobs))
+		return nil
+	default:
+		log.Warn().Msgf("Refusing %s event %s, %d events already queued", d.EventType, d.DeliveryID, len(q.jobs))
+		if err := q.store.remove(r); err != nil {
+			log.Error().Err(err).Msgf("Error removing refused %s event %s from the store", d.EventType, d.DeliveryID)
+		}
+		return githubapp.ErrCapacityExceeded
+	}
+}
+
+// Resume queues again the events of the store that were not handled before the last stop of the
+// server, to be handled by handler. The events whose handling was interrupted are not handled
+// again, they are given to handler if it is an InterruptedHandler.
+// It does not block: the events that don't fit in the queue, e.g. after its size was lowered, are
+// queued in the background as it empties, and stay in the store until then.
+func (q *Queue) Resume(handler githubapp.EventHandler) error {
+	records, err := q.store.pending()
+	if err != nil {
+		return err
+	}
+
+	overflow := []job{}
+	for _, r := range records {
+		if r.Running {
+			log.Info().Msgf("Handling of %s event %s was interrupted", r.EventType, r.DeliveryID)
+			if h, ok := handler.(InterruptedHandler); ok {
+				if err := h.HandleInterrupted(q.ctx, r.EventType, r.DeliveryID, r.Payload); err != nil {
+					log.Error().Err(err).Msgf("Error handling interrupted %s event %s", r.EventType, r.DeliveryID)
+				}
+			}
+			if err := q.store.remove(r); err != nil {
+				return err
+			}
+			continue
+		}
+
+		log.Info().Msgf("Resuming %s event %s queued at %s", r.EventType, r.DeliveryID, r.QueuedAt)
+		d := githubapp.Dispatch{Handler: handler, EventType: r.EventType, DeliveryID: r.DeliveryID, Payload: r.Payload}
+		j := job{dispatch: d, record: r}
+		// Once an event did not fit, the next ones wait as well to keep the order
+		if len(overflow) == 0 {
+			select {
+			case q.jobs <- j:
+				continue
+			default:
+			}
+		}
+		overflow = append(overflow, j)
+	}
+
+	if len(overflow) > 0 {
+		log.Warn().Msgf("%d resumed events don't fit in the queue, they are queued as it empties", len(overflow))
+		go q.enqueue(overflow)
+	}
+	return nil
+}
+
+// enqueue queues jobs as the queue empties, the ones not queued before the shutdown stay in the store.
+func (q *Queue) enqueue(jobs []job) {
+	for _, j := range jobs {
+		select {
+		case q.jobs <- j:
+		case <-q.stopping:
+			return
+		}
+	}
+}
+
+// Shutdown stops the queue: no more events are queued or started, and the running ones are
+// given until the deadline of ctx to end. Their context is cancelled shortly before it, so that
+// they can conclude their check runs in time. The queued events are left in the store, to be
+// resumed on the next start.
+func (q *Queue) Shutdown(ctx context.Context) {
+	q.stopOnce.Do(func() { close(q.stopping) })
+
+	done := make(chan struct{})
+	go func() {
+		q.stopped.Wait()
+		close(done)
+	}()
+
+	graceCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		cancelWait := min(cancelWaitTimeout, time.Until(deadline)/cancelWaitShare)
+		var cancel context.CancelFunc
+		graceCtx, cancel = context.WithDeadline(ctx, deadline.Add(-cancelWait))
+		defer cancel()
+	}
+
+	select {
+	case <-done:
+	case <-graceCtx.Done():
+		log.Warn().Msgf("Cancelling %d running events not ended in time", atomic.LoadInt64(&q.running))
+		q.cancel(errors.RunCancelledError("terraform-checker was stopped, please re-run the check"))
+		select {
+		case <-done:
+		case <-ctx.Done():
+			log.Error().Msgf("%d cancelled events still running", atomic.LoadInt64(&q.running))
+		}
+	}
+
+	if queued := len(q.jobs); queued > 0 {
+		if q.store != nil {
+			log.Info().Msgf("%d queued events left in the store", queued)
+		} else {
+			log.Warn().Msgf("%d queued events dropped", queued)
+		}
+	}
+}
+
+// Stats returns the current stats of the queue.
+func (q *Queue) Stats() Stats {
+	queued := len(q.jobs)
+	running := int(atomic.LoadInt64(&q.running))
+	return Stats{
+		Queued:   queued,
+		Running:  running,
+		Size:     cap(q.jobs),
+		Workers:  q.workers,
+		Capacity: cap(q.jobs) - queued,
+	}
+}
+
+func (q *Queue) isStopping() bool {
+	select {
+	case <-q.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *Queue) work() {
+	defer q.stopped.Done()
+	for {
+		select {
+		case <-q.stopping:
+			return
+		case j := <-q.jobs:
+			// Events taken while stopping stay in the store
+			if q.isStopping() {
+				log.Info().Msgf("%s event %s left queued, shutting down", j.dispatch.EventType, j.dispatch.DeliveryID)
+				return
+			}
+			q.execute(j)
+		}
+	}
+}
+
+func (q *Queue) execute(j job) {
+	atomic.AddInt64(&q.running, 1)
+	defer atomic.AddInt64(&q.running, -1)
+
+	if err := q.store.setRunning(j.record); err != nil {
+		log.Error().Err(err).Msgf("Error marking %s event %s as running in the store", j.dispatch.EventType, j.dispatch.DeliveryID)
+	}
+	defer func() {
+		if err := q.store.remove(j.record); err != nil {
+			log.Error().Err(err).Msgf("Error removing handled %s event %s from the store", j.dispatch.EventType, j.dispatch.DeliveryID)
+		}
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Msgf("Panic handling %s event %s: %v", j.dispatch.EventType, j.dispatch.DeliveryID, r)
+		}
+	}()
+
+	log.Debug().Msgf("Handling %s event %s, queued for %s", j.dispatch.EventType, j.dispatch.DeliveryID, time.Since(j.record.QueuedAt))
+	if err := j.dispatch.Execute(q.ctx); err != nil {
+		log.Error().Err(err).Msgf("Error handling %s event %s", j.dispatch.EventType, j.dispatch.DeliveryID)
+	}
+}
+
+// Handler returns the handler serving the stats of the queue as JSON.
+func (q *Queue) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(q.Stats()); err != nil {
+			log.Error().Err(err).Msg("Error writing queue stats")
+		}
+	}
+}
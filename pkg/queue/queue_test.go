@@ -0,0 +1,282 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/palantir/go-githubapp/githubapp"
+	"github.com/terraform-tools/terraform-checker/pkg/queue"
+)
+
+// blockingHandler handles events once release is closed.
+type blockingHandler struct {
+	started chan string
+	release chan struct{}
+}
+
+func (h *blockingHandler) Handles() []string {
+	return []string{"check_suite"}
+}
+
+func (h *blockingHandler) Handle(_ context.Context, _, deliveryID string, _ []byte) error {
+	h.started <- deliveryID
+	<-h.release
+	return nil
+}
+
+func newQueue(t *testing.T, size, workers int, store *queue.Store) *queue.Queue {
+	t.Helper()
+	q, err := queue.New(size, workers, store)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return q
+}
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+	handler := &blockingHandler{started: make(chan string, 10), release: make(chan struct{})}
+	q := newQueue(t, 2, 1, nil)
+	if _, err := queue.New(0, 1, nil); err == nil {
+		t.Errorf("Expected an error for a queue without size")
+	}
+
+	dispatch := func(id string) error {
+		return q.Schedule(context.Background(), githubapp.Dispatch{Handler: handler, EventType: "check_suite", DeliveryID: id})
+	}
+
+	// The first event is run by the worker, the next ones fill the queue
+	if err := dispatch("1"); err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+	if id := <-handler.started; id != "1" {
+		t.Fatalf("Expected event 1 to be handled first, got %v", id)
+	}
+	for _, id := range []string{"2", "3"} {
+		if err := dispatch(id); err != nil {
+			t.Fatalf("Schedule failed: %v", err)
+		}
+	}
+	if err := dispatch("4"); !errors.Is(err, githubapp.ErrCapacityExceeded) {
+		t.Errorf("Expected the event to be refused on a full queue, got %v", err)
+	}
+	if stats, expected := q.Stats(), (queue.Stats{Queued: 2, Running: 1, Size: 2, Workers: 1, Capacity: 0}); stats != expected {
+		t.Errorf("Expected stats %+v, got %+v", expected, stats)
+	}
+
+	close(handler.release)
+	for _, expected := range []string{"2", "3"} {
+		select {
+		case id := <-handler.started:
+			if id != expected {
+				t.Errorf("Expected event %v, got %v", expected, id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Event %v not handled", expected)
+		}
+	}
+}
+
+// recordingHandler records the handled and interrupted events.
+type recordingHandler struct {
+	handled     chan string
+	interrupted chan string
+}
+
+func (h *recordingHandler) Handles() []string {
+	return []string{"check_suite"}
+}
+
+func (h *recordingHandler) Handle(_ context.Context, _, deliveryID string, _ []byte) error {
+	h.handled <- deliveryID
+	return nil
+}
+
+func (h *recordingHandler) HandleInterrupted(_ context.Context, _, deliveryID string, _ []byte) error {
+	h.interrupted <- deliveryID
+	return nil
+}
+
+func TestQueueResume(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "queue.db")
+
+	// Event 1 is running and event 2 queued when the server stops
+	store, err := queue.OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	blocking := &blockingHandler{started: make(chan string, 10), release: make(chan struct{})}
+	defer close(blocking.release)
+	q := newQueue(t, 2, 1, store)
+	for _, id := range []string{"1", "2"} {
+		if err := q.Schedule(context.Background(), githubapp.Dispatch{Handler: blocking, EventType: "check_suite", DeliveryID: id}); err != nil {
+			t.Fatalf("Schedule failed: %v", err)
+		}
+	}
+	<-blocking.started
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	store, err = queue.OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	defer store.Close()
+	recording := &recordingHandler{handled: make(chan string, 10), interrupted: make(chan string, 10)}
+	if err := newQueue(t, 2, 1, store).Resume(recording); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if id := <-recording.interrupted; id != "1" {
+		t.Errorf("Expected event 1 to be interrupted, got %v", id)
+	}
+	select {
+	case id := <-recording.handled:
+		if id != "2" {
+			t.Errorf("Expected event 2 to be resumed, got %v", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Event 2 not resumed")
+	}
+}
+
+func TestQueueResumeFull(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "queue.db")
+
+	// Event 1 is running and events 2 to 4 queued when the server stops
+	store, err := queue.OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	blocking := &blockingHandler{started: make(chan string, 10), release: make(chan struct{})}
+	defer close(blocking.release)
+	q := newQueue(t, 3, 1, store)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		if err := q.Schedule(context.Background(), githubapp.Dispatch{Handler: blocking, EventType: "check_suite", DeliveryID: id}); err != nil {
+			t.Fatalf("Schedule failed: %v", err)
+		}
+		if id == "1" {
+			<-blocking.started
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// The queue size was lowered, the events that don't fit are queued once the first ones are handled
+	store, err = queue.OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	defer store.Close()
+	resumed := &blockingHandler{started: make(chan string, 10), release: make(chan struct{})}
+	resumedQueue := newQueue(t, 1, 1, store)
+	if err := resumedQueue.Resume(resumed); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	close(resumed.release)
+	for _, expected := range []string{"2", "3", "4"} {
+		select {
+		case id := <-resumed.started:
+			if id != expected {
+				t.Errorf("Expected event %v, got %v", expected, id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Event %v not resumed", expected)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resumedQueue.Shutdown(ctx)
+}
+
+// cancellableHandler handles events until their context is cancelled.
+type cancellableHandler struct {
+	started chan string
+	causes  chan error
+}
+
+func (h *cancellableHandler) Handles() []string {
+	return []string{"check_suite"}
+}
+
+func (h *cancellableHandler) Handle(ctx context.Context, _, deliveryID string, _ []byte) error {
+	h.started <- deliveryID
+	<-ctx.Done()
+	h.causes <- context.Cause(ctx)
+	return nil
+}
+
+func TestQueueShutdown(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "queue.db")
+	store, err := queue.OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+
+	handler := &cancellableHandler{started: make(chan string, 10), causes: make(chan error, 10)}
+	q := newQueue(t, 2, 1, store)
+	for _, id := range []string{"1", "2"} {
+		if err := q.Schedule(context.Background(), githubapp.Dispatch{Handler: handler, EventType: "check_suite", DeliveryID: id}); err != nil {
+			t.Fatalf("Schedule failed: %v", err)
+		}
+	}
+	<-handler.started
+
+	// The running event is cancelled before the deadline, the queued one is kept
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	q.Shutdown(ctx)
+	if cause := <-handler.causes; cause == nil || !strings.Contains(cause.Error(), "terraform-checker was stopped") {
+		t.Errorf("Unexpected cancellation cause %v", cause)
+	}
+	if err := q.Schedule(context.Background(), githubapp.Dispatch{Handler: handler, EventType: "check_suite", DeliveryID: "3"}); !errors.Is(err, githubapp.ErrCapacityExceeded) {
+		t.Errorf("Expected events to be refused once shut down, got %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	store, err = queue.OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	defer store.Close()
+	recording := &recordingHandler{handled: make(chan string, 10), interrupted: make(chan string, 10)}
+	if err := newQueue(t, 2, 1, store).Resume(recording); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if id := <-recording.handled; id != "2" {
+		t.Errorf("Expected event 2 to be resumed, got %v", id)
+	}
+	if len(recording.interrupted) != 0 {
+		t.Errorf("Expected the cancelled event not to be interrupted")
+	}
+}
+
+func TestQueueShutdownDeadline(t *testing.T) {
+	t.Parallel()
+	handler := &blockingHandler{started: make(chan string, 10), release: make(chan struct{})}
+	defer close(handler.release)
+	q := newQueue(t, 2, 1, nil)
+	if err := q.Schedule(context.Background(), githubapp.Dispatch{Handler: handler, EventType: "check_suite", DeliveryID: "1"}); err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+	<-handler.started
+
+	// The event ignores the cancellation of its context, the shutdown still ends at the deadline
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	q.Shutdown(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the shutdown to end at its deadline, took %s", elapsed)
+	}
+}
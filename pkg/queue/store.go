@@ -0,0 +1,121 @@
+package queue
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const storeOpenTimeout = 10 * time.Second
+
+var jobsBucket = []byte("jobs") //nolint:gochecknoglobals // bolt takes bucket names as byte slices
+
+// record is a job as persisted in the store.
+type record struct {
+	ID         uint64    `json:"-"`
+	EventType  string    `json:"event_type"`  //nolint:tagliatelle
+	DeliveryID string    `json:"delivery_id"` //nolint:tagliatelle
+	Payload    []byte    `json:"payload"`
+	QueuedAt   time.Time `json:"queued_at"` //nolint:tagliatelle
+	// Running is true once a worker started the job, whose handling is interrupted if the server stops
+	Running bool `json:"running"`
+}
+
+// Store persists the jobs of a Queue in a BoltDB file until they are handled, so that they
+// survive a restart of the server. A nil Store persists nothing.
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens the store of the BoltDB file at path, creating it if needed.
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: storeOpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func (s *Store) add(r *record) error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(jobsBucket)
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		r.ID = id
+		return put(b, r)
+	})
+}
+
+func (s *Store) setRunning(r *record) error {
+	if s == nil {
+		return nil
+	}
+	r.Running = true
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(jobsBucket), r)
+	})
+}
+
+func (s *Store) remove(r *record) error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Delete(key(r.ID))
+	})
+}
+
+// pending returns the jobs not handled yet, in their queuing order.
+func (s *Store) pending() ([]*record, error) {
+	records := []*record{}
+	if s == nil {
+		return records, nil
+	}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(k, v []byte) error {
+			r := &record{ID: binary.BigEndian.Uint64(k)}
+			if err := json.Unmarshal(v, r); err != nil {
+				return err
+			}
+			records = append(records, r)
+			return nil
+		})
+	})
+	return records, err
+}
+
+func put(b *bolt.Bucket, r *record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return b.Put(key(r.ID), data)
+}
+
+// key encodes ids in big endian so that the keys are sorted in queuing order.
+func key(id uint64) []byte {
+	k := make([]byte, 8) //nolint:gomnd // size of an uint64
+	binary.BigEndian.PutUint64(k, id)
+	return k
+}
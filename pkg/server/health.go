@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terraform-tools/terraform-checker/pkg/github"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+const (
+	healthCheckTimeout = 10 * time.Second
+	// readyzCacheTTL is how long the dependency statuses are reused, so that frequent probes don't
+	// spend the GitHub API rate limit and run the binaries each time
+	readyzCacheTTL = 30 * time.Second
+	// githubFailuresThreshold is the number of consecutive GitHub API errors making the server not
+	// ready, a transient error must not pull it out of rotation
+	githubFailuresThreshold = 3
+	// minFreeDiskSpace is the free space needed in the temp dir, where the repositories are cloned
+	minFreeDiskSpace = 1 << 30
+	bytesPerMiB      = 1 << 20
+
+	StatusOK      = "ok"
+	StatusError   = "error"
+	StatusMissing = "missing"
+	// StatusDegraded is the status of a failing dependency that does not make the server unready yet
+	StatusDegraded = "degraded"
+)
+
+// DependencyStatus is the status of a dependency of the server.
+type DependencyStatus struct {
+	Status  string `json:"status"`
+	Version string `json:"version,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+	// Optional dependencies don't make the server unhealthy when missing
+	Optional bool `json:"optional,omitempty"`
+}
+
+// HealthReport is the status of the server and of each of its dependencies.
+type HealthReport struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyStatus `json:"dependencies,omitempty"`
+}
+
+// HealthHandler serves /healthz, only telling that the server is up, and /readyz, checking the
+// binaries, the disk space and the GitHub credentials the checks need. A failing dependency must
+// not fail /healthz, the liveness probe would restart the server and kill the running checks.
+type HealthHandler struct {
+	CheckHandler *github.CheckHandler
+
+	mu             sync.Mutex
+	cached         HealthReport
+	cachedAt       time.Time
+	githubFailures int
+}
+
+func (h *HealthHandler) Healthz(w http.ResponseWriter, _ *http.Request) {
+	writeReport(w, HealthReport{Status: StatusOK})
+}
+
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, h.report(r.Context()))
+}
+
+// report returns the status of the dependencies, computed at most once per readyzCacheTTL. The
+// probes arriving meanwhile wait for it and share it.
+func (h *HealthHandler) report(ctx context.Context) HealthReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.cachedAt.IsZero() && time.Since(h.cachedAt) < readyzCacheTTL {
+		return h.cached
+	}
+
+	// The report is shared, it must not fail because the probe that computes it went away
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), healthCheckTimeout)
+	defer cancel()
+
+	versionsDir := h.CheckHandler.Registry.ExecConfig().VersionsDir
+	deps := map[string]DependencyStatus{
+		string(terraform.EngineTerraform): engineStatus(ctx, versionsDir, terraform.EngineTerraform, false),
+		string(terraform.EngineTofu):      engineStatus(ctx, versionsDir, terraform.EngineTofu, true),
+		terraform.TerragruntPath:          binaryStatus(ctx, terraform.TerragruntPath, true),
+		terraform.TfLintPath:              binaryStatus(ctx, terraform.TfLintPath, false),
+		"tflint_plugins":                  errorStatus(h.CheckHandler.TfLintInitErr),
+		"disk":                            diskStatus(os.TempDir()),
+		"github":                          h.githubStatus(ctx),
+	}
+
+	report := HealthReport{Status: StatusOK, Dependencies: deps}
+	for _, dep := range deps {
+		if dep.Status != StatusOK && dep.Status != StatusDegraded && !dep.Optional {
+			report.Status = StatusError
+		}
+	}
+	h.cached, h.cachedAt = report, time.Now()
+	return report
+}
+
+// githubStatus returns the status of the GitHub credentials, degraded until the API failed
+// githubFailuresThreshold times in a row.
+func (h *HealthHandler) githubStatus(ctx context.Context) DependencyStatus {
+	err := h.CheckHandler.CheckCredentials(ctx)
+	if err == nil {
+		h.githubFailures = 0
+		return DependencyStatus{Status: StatusOK}
+	}
+	h.githubFailures++
+	status := DependencyStatus{Status: StatusError, Detail: err.Error()}
+	if h.githubFailures < githubFailuresThreshold {
+		status.Status = StatusDegraded
+		status.Detail = fmt.Sprintf("%s, %d consecutive failures", err, h.githubFailures)
+	}
+	return status
+}
+
+func writeReport(w http.ResponseWriter, report HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Error().Err(err).Msg("Error writing health report")
+	}
+}
+
+// engineStatus returns the status of the binary of engine, or of its versions when they are
+// picked among the ones of versionsDir.
+func engineStatus(ctx context.Context, versionsDir string, engine terraform.Engine, optional bool) DependencyStatus {
+	if versionsDir == "" {
+		return binaryStatus(ctx, engine.Binary(), optional)
+	}
+
+	versions, err := terraform.InstalledTerraformVersions(engine, versionsDir)
+	if err != nil {
+		return DependencyStatus{Status: StatusError, Detail: err.Error(), Optional: optional}
+	}
+	if len(versions) == 0 {
+		return DependencyStatus{Status: StatusMissing, Detail: fmt.Sprintf("no version installed in %s", versionsDir), Optional: optional}
+	}
+	installed := make([]string, 0, len(versions))
+	for _, v := range versions {
+		installed = append(installed, v.Original())
+	}
+	return DependencyStatus{Status: StatusOK, Version: strings.Join(installed, ", "), Optional: optional}
+}
+
+func binaryStatus(ctx context.Context, binary string, optional bool) DependencyStatus {
+	version, err := terraform.BinaryVersion(ctx, binary)
+	if err != nil {
+		return DependencyStatus{Status: StatusMissing, Detail: err.Error(), Optional: optional}
+	}
+	return DependencyStatus{Status: StatusOK, Version: version, Optional: optional}
+}
+
+func errorStatus(err error) DependencyStatus {
+	if err != nil {
+		return DependencyStatus{Status: StatusError, Detail: err.Error()}
+	}
+	return DependencyStatus{Status: StatusOK}
+}
+
+func diskStatus(dir string) DependencyStatus {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return DependencyStatus{Status: StatusError, Detail: err.Error()}
+	}
+	free := stat.Bavail * uint64(stat.Bsize) //nolint:unconvert // Bsize is not an uint64 on every platform
+	status := DependencyStatus{Status: StatusOK, Detail: fmt.Sprintf("%d MiB free in %s", free/bytesPerMiB, dir)}
+	if free < minFreeDiskSpace {
+		status.Status = StatusError
+	}
+	return status
+}
@@ -0,0 +1,91 @@
+package server_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	gh "github.com/google/go-github/v56/github"
+	"github.com/palantir/go-githubapp/githubapp"
+	"github.com/terraform-tools/terraform-checker/pkg/github"
+	"github.com/terraform-tools/terraform-checker/pkg/server"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+type invalidCredentials struct {
+	githubapp.ClientCreator
+	calls *atomic.Int32
+}
+
+func (c invalidCredentials) NewAppClient() (*gh.Client, error) {
+	c.calls.Add(1)
+	return nil, errors.New("invalid private key")
+}
+
+func newTestHealthHandler(calls *atomic.Int32) *server.HealthHandler {
+	return &server.HealthHandler{CheckHandler: &github.CheckHandler{
+		Client:        invalidCredentials{calls: calls},
+		Registry:      terraform.DefaultRegistry(),
+		TfLintInitErr: errors.New("tflint --init failed: no network"),
+	}}
+}
+
+func decodeReport(t *testing.T, w *httptest.ResponseRecorder) server.HealthReport {
+	t.Helper()
+	var report server.HealthReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("Error decoding report: %v", err)
+	}
+	return report
+}
+
+func TestHealthz(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	newTestHealthHandler(&atomic.Int32{}).Healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %v whatever the dependencies, got %v", http.StatusOK, w.Code)
+	}
+	if report := decodeReport(t, w); report.Status != server.StatusOK || len(report.Dependencies) > 0 {
+		t.Errorf("Expected the dependencies to be checked by readyz only, got %+v", report)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	t.Parallel()
+
+	calls := &atomic.Int32{}
+	handler := newTestHealthHandler(calls)
+	w := httptest.NewRecorder()
+	handler.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %v, got %v", http.StatusServiceUnavailable, w.Code)
+	}
+
+	report := decodeReport(t, w)
+	if report.Status != server.StatusError {
+		t.Errorf("Expected report status %v, got %v", server.StatusError, report.Status)
+	}
+	if dep := report.Dependencies["tflint_plugins"]; dep.Status != server.StatusError || dep.Detail != "tflint --init failed: no network" {
+		t.Errorf("Unexpected tflint plugins status %+v", dep)
+	}
+	// A single GitHub error does not make the server unready
+	if dep := report.Dependencies["github"]; dep.Status != server.StatusDegraded || !strings.HasPrefix(dep.Detail, "invalid private key") {
+		t.Errorf("Unexpected github status %+v", dep)
+	}
+	if dep := report.Dependencies["disk"]; dep.Detail == "" {
+		t.Errorf("Expected the free disk space to be reported, got %+v", dep)
+	}
+
+	// The dependencies are checked again only once the cached report expired
+	w = httptest.NewRecorder()
+	handler.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if cached := decodeReport(t, w); cached.Dependencies["github"] != report.Dependencies["github"] || calls.Load() != 1 {
+		t.Errorf("Expected the report to be cached, got %+v after %d GitHub calls", cached, calls.Load())
+	}
+}
@@ -1,23 +1,48 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/palantir/go-githubapp/githubapp"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
 	"github.com/terraform-tools/terraform-checker/pkg/config"
+	"github.com/terraform-tools/terraform-checker/pkg/errors"
 	"github.com/terraform-tools/terraform-checker/pkg/github"
+	"github.com/terraform-tools/terraform-checker/pkg/metrics"
+	"github.com/terraform-tools/terraform-checker/pkg/queue"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
 )
 
 const (
-	ListeningPort            = 8000
 	ReadHeaderTimeoutSeconds = 3
 )
 
-func StartServer() {
-	config := config.LoadConfig()
+// StartServer runs the server until it gets SIGTERM or SIGINT. The running checks are given the
+// grace period of the config to end, after which they are cancelled.
+// The config fields are overridden by flags.
+func StartServer(flags config.Overrides) error {
+	watcher, err := config.NewWatcher(config.Path(), flags)
+	if err != nil {
+		return err
+	}
+	config := watcher.Current()
+	// The config does not know the built-in checks, whose names the plugins must not take
+	for _, p := range config.Plugins {
+		if err := terraform.ValidatePlugin(p); err != nil {
+			return errors.ConfigNotValidError(fmt.Sprintf("invalid plugin in plugins field: %v", err))
+		}
+	}
 
 	cc, err := githubapp.NewDefaultCachingClientCreator(config.GithubHubAppConfig)
 	if err != nil {
@@ -27,26 +52,95 @@ func StartServer() {
 	mainHandler := &github.CheckHandler{Client: cc, Config: config}
 	mainHandler.Init()
 
-	webhookHandler := githubapp.NewDefaultEventDispatcher(config.GithubHubAppConfig, mainHandler)
+	// Events are handled by the queue workers once GitHub got its response, and persisted
+	// until then if a queue path is set
+	var store *queue.Store
+	if config.QueuePath != "" {
+		if store, err = queue.OpenStore(config.QueuePath); err != nil {
+			log.Fatal().Err(err).Msg("Error opening queue store")
+		}
+		defer store.Close()
+	}
+	jobQueue, err := queue.New(config.QueueSize, config.QueueWorkers, store)
+	if err != nil {
+		return err
+	}
+	if err := jobQueue.Resume(mainHandler); err != nil {
+		log.Error().Err(err).Msg("Error resuming queued events")
+	}
+	metrics.RegisterQueue(
+		func() int { return jobQueue.Stats().Queued },
+		func() int { return jobQueue.Stats().Running },
+	)
+	webhookHandler := githubapp.NewEventDispatcher(
+		[]githubapp.EventHandler{mainHandler},
+		config.GithubHubAppConfig.App.WebhookSecret,
+		githubapp.WithScheduler(jobQueue),
+	)
 
+	// Every route is served under the base path, e.g. to share an ingress
+	route := func(path string) string {
+		return strings.TrimSuffix(config.BasePath, "/") + path
+	}
 	mux := http.NewServeMux()
-	mux.Handle(githubapp.DefaultWebhookRoute, webhookHandler)
-	mux.HandleFunc("/ping", PingHandler)
-	log.Info().Msgf("Starting webserver, listening :%d", ListeningPort)
+	mux.Handle(route(githubapp.DefaultWebhookRoute), webhookHandler)
+	mux.HandleFunc(route("/ping"), PingHandler)
+	healthHandler := &HealthHandler{CheckHandler: mainHandler}
+	mux.HandleFunc(route("/healthz"), healthHandler.Healthz)
+	mux.HandleFunc(route("/readyz"), healthHandler.Readyz)
+	mux.HandleFunc(route("/queue"), jobQueue.Handler())
+	mux.Handle(route("/metrics"), promhttp.Handler())
 
 	server := &http.Server{
-		Addr:              fmt.Sprintf(":%d", ListeningPort),
+		Addr:              net.JoinHostPort(config.ListenAddress, strconv.Itoa(config.ListenPort)),
 		ReadHeaderTimeout: ReadHeaderTimeoutSeconds * time.Second,
 		Handler:           mux,
 	}
+	if config.TLSCertFile != "" {
+		certs, err := NewCertReloader(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = &tls.Config{GetCertificate: certs.GetCertificate, MinVersion: tls.VersionTLS12}
+	}
 
-	err = server.ListenAndServe()
-	if err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	// The fields of the config read for each event are reloaded, on SIGHUP or when the file changes
+	go watcher.Run(ctx, mainHandler.SetConfig)
+	serverErr := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			log.Info().Msgf("Starting webserver, listening %s with TLS under %s", server.Addr, route("/"))
+			serverErr <- server.ListenAndServeTLS("", "")
+			return
+		}
+		log.Info().Msgf("Starting webserver, listening %s under %s", server.Addr, route("/"))
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serverErr:
 		log.Error().Err(err).Msg("Error creating webserver")
+	case <-ctx.Done():
+		log.Info().Msgf("Shutting down, waiting up to %s for the running checks", config.ShutdownGracePeriod)
 	}
-	if err != nil {
-		panic(err)
+	shutdown(server, jobQueue, mainHandler, config.ShutdownGracePeriod)
+	return err
+}
+
+// shutdown stops accepting webhooks, then stops the queue and the plugins once the running
+// events ended or were cancelled. Each stage gets the time left of the grace period.
+func shutdown(server *http.Server, jobQueue *queue.Queue, mainHandler *github.CheckHandler, gracePeriod time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("Error shutting down webserver")
 	}
+
+	jobQueue.Shutdown(ctx)
+	mainHandler.Plugins.Kill()
+	log.Info().Msg("Shut down")
 }
 
 func PingHandler(w http.ResponseWriter, _ *http.Request) {
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CertReloader serves the TLS certificate of a cert and a key file, loaded again when one of
+// them changes, e.g. when it is renewed by cert-manager.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	lock    sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// NewCertReloader returns a CertReloader of the files, failing if they can't be loaded.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	c := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate is the tls.Config GetCertificate func, it reloads the certificate if its files
+// changed since the last load. On errors, the previous certificate is kept.
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.changed() {
+		if err := c.reload(); err != nil {
+			log.Error().Err(err).Msgf("Error reloading TLS certificate %s, keeping the previous one", c.certFile)
+		} else {
+			log.Info().Msgf("Reloaded TLS certificate %s", c.certFile)
+		}
+	}
+	return c.cert, nil
+}
+
+func (c *CertReloader) changed() bool {
+	certMod, keyMod := modTime(c.certFile), modTime(c.keyFile)
+	return !certMod.Equal(c.certMod) || !keyMod.Equal(c.keyMod)
+}
+
+// reload loads the certificate, the files are not loaded again until they change even if it fails.
+func (c *CertReloader) reload() error {
+	c.certMod, c.keyMod = modTime(c.certFile), modTime(c.keyFile)
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.cert = &cert
+	return nil
+}
+
+// modTime returns the modification time of the file, following symlinks as the files of the
+// Kubernetes secrets are.
+func modTime(file string) time.Time {
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -1,64 +1,33 @@
 package terraform
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v56/github"
+	"github.com/hashicorp/hcl/v2"
 	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/rs/zerolog/log"
 	"github.com/shurcooL/githubv4"
 	"github.com/terraform-linters/tflint/formatter"
+	"github.com/terraform-tools/terraform-checker/pkg/metrics"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin"
 )
 
-// TfCheckType defines the different possible terraform checks.
-type TfCheckType int64
-
-const (
-	Fmt TfCheckType = iota
-	Validate
-	TFLint
-)
-
-func (t TfCheckType) String() string {
-	switch t {
-	case Fmt:
-		return "fmt"
-	case Validate:
-		return "validate"
-	case TFLint:
-		return "tflint"
-	default:
-		return "not implemented"
-	}
-}
-
-func TfCheckTypeFromString(s string) TfCheckType {
-	switch s {
-	case "fmt":
-		return Fmt
-	case "validate":
-		return Validate
-	case "tflint":
-		return TFLint
-	default:
-		return -1
-	}
-}
-
-func AllTfCheckTypes() []string {
-	return []string{
-		Fmt.String(),
-		Validate.String(),
-		TFLint.String(),
-	}
-}
-
 // TfCheck interface defines all functions that should be present for any TfCheck.
 type TfCheck interface {
 	Name() string
-	Type() TfCheckType
-	Run()
+	Run(ctx context.Context)
 	Dir() string
 	RelDir() string
+	// Engine is the engine of the dir, resolved once for all of its checks
+	Engine() Engine
 	IsOK() bool
 	Output() string
 	FailureConclusion() githubv4.CheckConclusionState
@@ -66,11 +35,65 @@ type TfCheck interface {
 	Annotations() []*github.CheckRunAnnotation
 }
 
+// SetChangedFiles gives the checks that need them the files changed by the pull request,
+// relative to cloneDir.
+func SetChangedFiles(checks []TfCheck, cloneDir string, changedFiles []string) {
+	for _, check := range checks {
+		if c, ok := check.(interface{ setChangedFiles(files []string) }); ok {
+			files := []string{}
+			for _, file := range changedFiles {
+				if path := filepath.Join(cloneDir, file); isSubDir(check.Dir(), filepath.Dir(path)) {
+					rel, _ := filepath.Rel(check.Dir(), path)
+					files = append(files, rel)
+				}
+			}
+			c.setChangedFiles(files)
+		}
+	}
+}
+
+// RunCheck runs a check, within timeout if it is not zero. A check whose context is done before
+// its end is marked as timed out, as well as the failing ones sharing a terraform init that timed out.
+func RunCheck(ctx context.Context, check TfCheck, timeout time.Duration) {
+	checkCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		checkCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	defer func() {
+		outcome := string(githubv4.CheckConclusionStateSuccess)
+		if !check.IsOK() {
+			outcome = string(check.FailureConclusion())
+		}
+		metrics.ObserveCheck(check.Name(), outcome, start)
+	}()
+	check.Run(checkCtx)
+
+	c, ok := check.(interface {
+		setTimedOut(timeout time.Duration)
+		initTimedOut() bool
+	})
+	if !ok {
+		return
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded), !check.IsOK() && c.initTimedOut():
+		// The timeout of the whole run, or of another check, was reached
+		c.setTimedOut(0)
+	case errors.Is(checkCtx.Err(), context.DeadlineExceeded):
+		c.setTimedOut(timeout)
+	}
+}
+
 type TfCheckFields struct {
-	dir     string
-	relDir  string
-	checkOk bool
-	output  string
+	dir      string
+	relDir   string
+	checkOk  bool
+	output   string
+	tfExec   *TfExec
+	timedOut bool
 }
 
 func NewTfCheckFields(dir, relDir string) TfCheckFields {
@@ -80,6 +103,44 @@ func NewTfCheckFields(dir, relDir string) TfCheckFields {
 	}
 }
 
+// setTfExec shares a TfExec between the checks of a dir.
+func (t *TfCheckFields) setTfExec(e *TfExec) {
+	t.tfExec = e
+}
+
+// exec returns the TfExec of the check, a check created on its own gets its own TfExec
+// running terraform with the default ExecConfig.
+func (t *TfCheckFields) exec() *TfExec {
+	if t.tfExec == nil {
+		t.tfExec = NewTfExec(t.dir, ExecConfig{})
+	}
+	return t.tfExec
+}
+
+// setTimedOut marks the check as failed because it did not end within timeout, zero if unknown.
+func (t *TfCheckFields) setTimedOut(timeout time.Duration) {
+	t.checkOk = false
+	t.timedOut = true
+	message := "Timed out"
+	if timeout > 0 {
+		message = fmt.Sprintf("Timed out after %s", timeout)
+	}
+	t.output = strings.TrimSpace(message + "\n" + t.output)
+}
+
+// initTimedOut returns true if the terraform init shared with the other checks of the dir timed out.
+func (t *TfCheckFields) initTimedOut() bool {
+	return t.tfExec != nil && t.tfExec.initTimedOut
+}
+
+// failureConclusion returns the conclusion of the check when it fails, timed out checks excepted.
+func (t *TfCheckFields) failureConclusion(conclusion githubv4.CheckConclusionState) githubv4.CheckConclusionState {
+	if t.timedOut {
+		return githubv4.CheckConclusionStateTimedOut
+	}
+	return conclusion
+}
+
 func (t *TfCheckFields) Dir() string {
 	return t.dir
 }
@@ -88,6 +149,10 @@ func (t *TfCheckFields) RelDir() string {
 	return t.relDir
 }
 
+func (t *TfCheckFields) Engine() Engine {
+	return t.exec().Engine()
+}
+
 func (t *TfCheckFields) IsOK() bool {
 	return t.checkOk
 }
@@ -102,6 +167,21 @@ type TfCheckFmt struct {
 	TfCheckFields
 }
 
+func fmtCheckDefinition() CheckDefinition {
+	return CheckDefinition{
+		Name:           Fmt,
+		Description:    "terraform fmt",
+		DefaultEnabled: true,
+		Factory: func(tfDir, relDir string) TfCheck {
+			return NewTfCheckFmt(tfDir, relDir)
+		},
+		TerragruntFactory: func(tfDir, relDir string) TfCheck {
+			return NewTfCheckTerragruntFmt(tfDir, relDir)
+		},
+		Fix: fixTfDirFmt,
+	}
+}
+
 func NewTfCheckFmt(tfDir, relDir string) *TfCheckFmt {
 	return &TfCheckFmt{
 		NewTfCheckFields(tfDir, relDir),
@@ -109,21 +189,17 @@ func NewTfCheckFmt(tfDir, relDir string) *TfCheckFmt {
 }
 
 func (t *TfCheckFmt) Name() string {
-	return Fmt.String()
-}
-
-func (t *TfCheckFmt) Type() TfCheckType {
 	return Fmt
 }
 
-func (t *TfCheckFmt) Run() {
-	ok, out := CheckTfFmt(t.dir)
+func (t *TfCheckFmt) Run(ctx context.Context) {
+	ok, out := CheckTfFmt(ctx, t.exec())
 	t.checkOk = ok
 	t.output = out
 }
 
 func (t *TfCheckFmt) FailureConclusion() githubv4.CheckConclusionState {
-	return githubv4.CheckConclusionStateFailure
+	return t.failureConclusion(githubv4.CheckConclusionStateFailure)
 }
 
 func (t *TfCheckFmt) FixAction() *github.CheckRunAction {
@@ -148,6 +224,20 @@ type TfCheckValidate struct {
 	tfValidateOutput *tfjson.ValidateOutput
 }
 
+func validateCheckDefinition() CheckDefinition {
+	return CheckDefinition{
+		Name:           Validate,
+		Description:    "terraform validate",
+		DefaultEnabled: true,
+		Factory: func(tfDir, relDir string) TfCheck {
+			return NewTfCheckValidate(tfDir, relDir)
+		},
+		TerragruntFactory: func(tfDir, relDir string) TfCheck {
+			return NewTfCheckTerragruntValidate(tfDir, relDir)
+		},
+	}
+}
+
 func NewTfCheckValidate(tfDir, relDir string) *TfCheckValidate {
 	return &TfCheckValidate{
 		TfCheckFields: NewTfCheckFields(tfDir, relDir),
@@ -155,22 +245,18 @@ func NewTfCheckValidate(tfDir, relDir string) *TfCheckValidate {
 }
 
 func (t *TfCheckValidate) Name() string {
-	return Validate.String()
-}
-
-func (t *TfCheckValidate) Type() TfCheckType {
 	return Validate
 }
 
-func (t *TfCheckValidate) Run() {
-	ok, out, tfValidateOutput := CheckTfValidate(t.dir)
+func (t *TfCheckValidate) Run(ctx context.Context) {
+	ok, out, tfValidateOutput := CheckTfValidate(ctx, t.exec())
 	t.checkOk = ok
 	t.output = out
 	t.tfValidateOutput = tfValidateOutput
 }
 
 func (t *TfCheckValidate) FailureConclusion() githubv4.CheckConclusionState {
-	return githubv4.CheckConclusionStateFailure
+	return t.failureConclusion(githubv4.CheckConclusionStateFailure)
 }
 
 func (t *TfCheckValidate) FixAction() *github.CheckRunAction {
@@ -216,6 +302,93 @@ func (t *TfCheckValidate) Annotations() (annotations []*github.CheckRunAnnotatio
 	return annotations
 }
 
+// Terragrunt fmt
+
+type TfCheckTerragruntFmt struct {
+	TfCheckFmt
+}
+
+func NewTfCheckTerragruntFmt(tfDir, relDir string) *TfCheckTerragruntFmt {
+	return &TfCheckTerragruntFmt{
+		*NewTfCheckFmt(tfDir, relDir),
+	}
+}
+
+func (t *TfCheckTerragruntFmt) Run(ctx context.Context) {
+	ok, out := CheckTerragruntFmt(ctx, t.exec())
+	t.checkOk = ok
+	t.output = out
+}
+
+// Terragrunt validate
+
+type TfCheckTerragruntValidate struct {
+	TfCheckValidate
+	inputs TerragruntInputs
+}
+
+func NewTfCheckTerragruntValidate(tfDir, relDir string) *TfCheckTerragruntValidate {
+	return &TfCheckTerragruntValidate{
+		TfCheckValidate: *NewTfCheckValidate(tfDir, relDir),
+	}
+}
+
+func (t *TfCheckTerragruntValidate) Run(ctx context.Context) {
+	ok, out, inputs, tfValidateOutput := CheckTerragruntValidate(ctx, t.exec())
+	t.checkOk = ok
+	t.output = out
+	t.inputs = inputs
+	t.tfValidateOutput = tfValidateOutput
+}
+
+// Annotations are all reported on the terragrunt.hcl of the unit, the diagnostics of terraform
+// refer to the configuration generated in the terragrunt cache dir.
+func (t *TfCheckTerragruntValidate) Annotations() (annotations []*github.CheckRunAnnotation) {
+	config, err := parseTerragruntConfig(t.dir)
+	if err != nil {
+		log.Error().Err(err).Msgf("error parsing %s of %s", terragruntConfigName, t.dir)
+		return annotations
+	}
+	path := fmt.Sprintf("%s/%s", t.RelDir(), terragruntConfigName)
+	newAnnotation := func(title, message string, level githubv4.CheckAnnotationLevel, r hcl.Range) *github.CheckRunAnnotation {
+		return &github.CheckRunAnnotation{
+			Title:           github.String(title),
+			Message:         github.String(message),
+			Path:            github.String(path),
+			AnnotationLevel: github.String(strings.ToLower(string(level))),
+			StartLine:       github.Int(r.Start.Line),
+			EndLine:         github.Int(r.End.Line),
+		}
+	}
+
+	for _, input := range t.inputs.Unused {
+		r, ok := config.inputRanges[input]
+		if !ok {
+			r = config.inputsRange
+		}
+		annotations = append(annotations, newAnnotation("Unused input",
+			fmt.Sprintf("Input %s is not a variable of the module", input), githubv4.CheckAnnotationLevelWarning, r))
+	}
+	for _, input := range t.inputs.Missing {
+		annotations = append(annotations, newAnnotation("Missing input",
+			fmt.Sprintf("Required input %s is not set", input), githubv4.CheckAnnotationLevelFailure, config.inputsRange))
+	}
+
+	if t.tfValidateOutput == nil || t.tfValidateOutput.Valid {
+		return annotations
+	}
+	for _, diag := range t.tfValidateOutput.Diagnostics {
+		message := diag.Detail
+		if diag.Range != nil && diag.Range.Filename != "" {
+			message = fmt.Sprintf("%s:%d: %s", diag.Range.Filename, diag.Range.Start.Line, diag.Detail)
+		}
+		annotation := newAnnotation(diag.Summary, message, githubv4.CheckAnnotationLevelFailure, config.sourceRange)
+		annotation.AnnotationLevel = TfValidateSeverityToAnnotationLevel(diag.Severity)
+		annotations = append(annotations, annotation)
+	}
+	return annotations
+}
+
 // TFLint
 
 type TfCheckTfLint struct {
@@ -223,6 +396,18 @@ type TfCheckTfLint struct {
 	tfLintOutput *formatter.JSONOutput
 }
 
+func tfLintCheckDefinition() CheckDefinition {
+	return CheckDefinition{
+		Name:           TFLint,
+		Description:    "tflint",
+		DefaultEnabled: true,
+		Factory: func(tfDir, relDir string) TfCheck {
+			return NewTfCheckTfLint(tfDir, relDir)
+		},
+		Fix: fixTfDirTfLint,
+	}
+}
+
 func NewTfCheckTfLint(tfDir, relDir string) *TfCheckTfLint {
 	return &TfCheckTfLint{
 		TfCheckFields: NewTfCheckFields(tfDir, relDir),
@@ -230,22 +415,18 @@ func NewTfCheckTfLint(tfDir, relDir string) *TfCheckTfLint {
 }
 
 func (t *TfCheckTfLint) Name() string {
-	return TFLint.String()
-}
-
-func (t *TfCheckTfLint) Type() TfCheckType {
 	return TFLint
 }
 
-func (t *TfCheckTfLint) Run() {
-	ok, out, tfLintOutput := CheckTfLint(t.dir)
+func (t *TfCheckTfLint) Run(ctx context.Context) {
+	ok, out, tfLintOutput := CheckTfLint(ctx, t.exec())
 	t.checkOk = ok
 	t.output = out
 	t.tfLintOutput = tfLintOutput
 }
 
 func (t *TfCheckTfLint) FailureConclusion() githubv4.CheckConclusionState {
-	return githubv4.CheckConclusionStateFailure
+	return t.failureConclusion(githubv4.CheckConclusionStateFailure)
 }
 
 func (t *TfCheckTfLint) FixAction() *github.CheckRunAction {
@@ -290,22 +471,204 @@ func (t *TfCheckTfLint) Annotations() (annotations []*github.CheckRunAnnotation)
 	return annotations
 }
 
-func NewTfCheck(checkType TfCheckType, tfDir, relDir string) TfCheck {
-	switch checkType {
-	case Fmt:
-		return NewTfCheckFmt(tfDir, relDir)
-	case Validate:
-		return NewTfCheckValidate(tfDir, relDir)
-	case TFLint:
-		return NewTfCheckTfLint(tfDir, relDir)
-	default:
+// Lockfile
+
+type TfCheckLockfile struct {
+	TfCheckFields
+	lockfile  []byte
+	platforms []string
+	required  bool
+	issues    []*LockfileIssue
+}
+
+func lockfileCheckDefinition() CheckDefinition {
+	return CheckDefinition{
+		Name:           Lockfile,
+		Description:    "lock file",
+		DefaultEnabled: true,
+		Factory: func(tfDir, relDir string) TfCheck {
+			return NewTfCheckLockfile(tfDir, relDir)
+		},
+		Fix: func(ctx context.Context, config ExecConfig, tfDir *TfDir, _ string) error {
+			return fixTfDirLockfile(ctx, config, tfDir)
+		},
+	}
+}
+
+func NewTfCheckLockfile(tfDir, relDir string) *TfCheckLockfile {
+	conf := NewTfDir(tfDir)
+
+	// The lock file is read right away, as the terraform init of the other checks
+	// of the same dir would otherwise update it before this check is run.
+	lockfile, err := os.ReadFile(filepath.Join(tfDir, tfLockfileName))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Error().Err(err).Msgf("error reading lock file of %s", tfDir)
+		}
+		lockfile = nil
+	}
+
+	return &TfCheckLockfile{
+		TfCheckFields: NewTfCheckFields(tfDir, relDir),
+		lockfile:      lockfile,
+		platforms:     conf.LockfilePlatforms(),
+		required:      conf.IsLockfileRequired(),
+	}
+}
+
+func (t *TfCheckLockfile) Name() string {
+	return Lockfile
+}
+
+func (t *TfCheckLockfile) Run(ctx context.Context) {
+	ok, out, issues := CheckTfLockfile(ctx, t.exec(), t.lockfile, t.platforms, t.required, t.exec().config.ProviderInstallation.providersLockOptions()...)
+	t.checkOk = ok
+	t.output = out
+	t.issues = issues
+}
+
+// FailureConclusion is neutral when the provider hashes could not be verified, the lock file
+// being possibly right.
+func (t *TfCheckLockfile) FailureConclusion() githubv4.CheckConclusionState {
+	if lockfileUnverified(t.issues) {
+		return t.failureConclusion(githubv4.CheckConclusionStateNeutral)
+	}
+	return t.failureConclusion(githubv4.CheckConclusionStateFailure)
+}
+
+func (t *TfCheckLockfile) FixAction() *github.CheckRunAction {
+	if lockfileUnverified(t.issues) {
 		return nil
 	}
+	return &github.CheckRunAction{
+		// Max length 20 characters
+		Label: "Fix lock file",
+		// Max length 40 characters
+		Description: "Add a terraform providers lock commit",
+		// Max length 20 characters
+		Identifier: t.Name(),
+	}
 }
 
-func GetTfChecks(tfDir, relDir string, checkTypes []string) (checks []TfCheck) {
-	for _, c := range checkTypes {
-		checks = append(checks, NewTfCheck(TfCheckTypeFromString(c), tfDir, relDir))
+func (t *TfCheckLockfile) Annotations() (annotations []*github.CheckRunAnnotation) {
+	for _, issue := range t.issues {
+		currentIssue := issue
+		level := githubv4.CheckAnnotationLevelFailure
+		if currentIssue.Unverified {
+			level = githubv4.CheckAnnotationLevelNotice
+		}
+
+		newAnnotation := github.CheckRunAnnotation{
+			Title:           github.String(currentIssue.Summary),
+			Message:         &currentIssue.Detail,
+			Path:            github.String(fmt.Sprintf("%s/%s", t.RelDir(), filepath.Base(currentIssue.Range.Filename))),
+			AnnotationLevel: github.String(strings.ToLower(string(level))),
+			StartLine:       github.Int(currentIssue.Range.Start.Line),
+			EndLine:         github.Int(currentIssue.Range.End.Line),
+		}
+
+		// Only set StarColumn/EndColumn if StartLine/Endline are on same line
+		if currentIssue.Range.Start.Line == currentIssue.Range.End.Line {
+			newAnnotation.StartColumn = github.Int(currentIssue.Range.Start.Column)
+			newAnnotation.EndColumn = github.Int(currentIssue.Range.End.Column)
+		}
+
+		annotations = append(annotations, &newAnnotation)
 	}
-	return
+
+	return annotations
+}
+
+// Plugin
+
+type TfCheckPlugin struct {
+	TfCheckFields
+	name         string
+	check        plugin.Check
+	err          error
+	result       plugin.Result
+	changedFiles []string
+}
+
+// NewTfCheckPlugin returns the check of a plugin, err is reported when the plugin check could not be created.
+func NewTfCheckPlugin(name string, check plugin.Check, err error, tfDir, relDir string) *TfCheckPlugin {
+	return &TfCheckPlugin{
+		TfCheckFields: NewTfCheckFields(tfDir, relDir),
+		name:          name,
+		check:         check,
+		err:           err,
+	}
+}
+
+func (t *TfCheckPlugin) Name() string {
+	return t.name
+}
+
+func (t *TfCheckPlugin) setChangedFiles(files []string) {
+	t.changedFiles = files
+}
+
+func (t *TfCheckPlugin) Run(ctx context.Context) {
+	if t.err == nil {
+		req := plugin.Request{Dir: t.dir, RelDir: t.relDir, ChangedFiles: t.changedFiles}
+		if c, ok := t.check.(plugin.ContextCheck); ok {
+			t.result, t.err = c.RunContext(ctx, req)
+		} else {
+			t.result, t.err = t.check.Run(req)
+		}
+	}
+	if t.err != nil {
+		log.Error().Err(t.err).Msgf("error running plugin %s", t.name)
+		t.checkOk = false
+		t.output = t.err.Error()
+		return
+	}
+	t.checkOk = t.result.OK()
+	t.output = t.result.Output()
+}
+
+func (t *TfCheckPlugin) FailureConclusion() githubv4.CheckConclusionState {
+	return t.failureConclusion(githubv4.CheckConclusionStateFailure)
+}
+
+func (t *TfCheckPlugin) FixAction() *github.CheckRunAction {
+	if t.result == nil || t.result.FixAction() == nil {
+		return nil
+	}
+	return &github.CheckRunAction{
+		// Max length 20 characters
+		Label: truncate(t.result.FixAction().Label, maxActionLabelLength),
+		// Max length 40 characters
+		Description: truncate(t.result.FixAction().Description, maxActionDescriptionLength),
+		// Max length 20 characters
+		Identifier: t.Name(),
+	}
+}
+
+func (t *TfCheckPlugin) Annotations() (annotations []*github.CheckRunAnnotation) {
+	if t.result == nil {
+		return annotations
+	}
+	for _, annotation := range t.result.Annotations() {
+		currentAnnotation := annotation
+
+		// StartLine/EndLine are mandatory
+		if currentAnnotation.Path == "" || currentAnnotation.StartLine == 0 {
+			continue
+		}
+		if currentAnnotation.EndLine < currentAnnotation.StartLine {
+			currentAnnotation.EndLine = currentAnnotation.StartLine
+		}
+
+		annotations = append(annotations, &github.CheckRunAnnotation{
+			Title:           github.String(currentAnnotation.Title),
+			Message:         &currentAnnotation.Message,
+			Path:            github.String(fmt.Sprintf("%s/%s", t.RelDir(), currentAnnotation.Path)),
+			AnnotationLevel: PluginSeverityToAnnotationLevel(currentAnnotation.Severity),
+			StartLine:       github.Int(currentAnnotation.StartLine),
+			EndLine:         github.Int(currentAnnotation.EndLine),
+		})
+	}
+
+	return annotations
 }
@@ -1,6 +1,8 @@
 package terraform_test
 
 import (
+	"context"
+	"os"
 	"path"
 	"path/filepath"
 	"testing"
@@ -31,7 +33,7 @@ func TestCheckTfFmt(t *testing.T) {
 		tc := tc
 		t.Run(tc.directory, func(t *testing.T) {
 			t.Parallel()
-			ok, msg := terraform.CheckTfFmt(path.Join(testDir, tc.directory))
+			ok, msg := terraform.CheckTfFmt(context.Background(), terraform.NewTfExec(path.Join(testDir, tc.directory), terraform.ExecConfig{}))
 			if ok != tc.output {
 				t.Errorf("CheckTfDir failed for dir %v, expected %v, got %v, message %v", tc.directory, tc.output, ok, msg)
 			}
@@ -62,10 +64,47 @@ func TestCheckTfValidate(t *testing.T) {
 		tc := tc
 		t.Run(tc.directory, func(t *testing.T) {
 			t.Parallel()
-			ok, msg, _ := terraform.CheckTfValidate(path.Join(testDir, tc.directory))
+			ok, msg, _ := terraform.CheckTfValidate(context.Background(), terraform.NewTfExec(path.Join(testDir, tc.directory), terraform.ExecConfig{}))
 			if ok != tc.output {
 				t.Errorf("CheckTfDir failed for dir %v, expected %v, got %v, message %v", tc.directory, tc.output, ok, msg)
 			}
 		})
 	}
 }
+
+func TestTfExecInitOnce(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	// A module call makes terraform init create the .terraform dir
+	if err := os.WriteFile(path.Join(dir, "main.tf"), []byte("module \"m\" {\n  source    = \"./m\"\n}\n"), 0o600); err != nil {
+		t.Fatalf("Error writing fixture %v", err)
+	}
+	if err := os.MkdirAll(path.Join(dir, "m"), 0o755); err != nil {
+		t.Fatalf("Error writing fixture %v", err)
+	}
+	tfExec := terraform.NewTfExec(dir, terraform.ExecConfig{})
+
+	// fmt does not need terraform init
+	if ok, _ := terraform.CheckTfFmt(context.Background(), tfExec); ok {
+		t.Errorf("Expected CheckTfFmt to fail")
+	}
+	if _, err := os.Stat(path.Join(dir, ".terraform")); err == nil {
+		t.Errorf("CheckTfFmt initialized the dir")
+	}
+
+	if ok, out, _ := tfExec.Init(context.Background()); !ok {
+		t.Fatalf("Init failed: %v", out)
+	}
+	if _, err := os.Stat(path.Join(dir, ".terraform")); err != nil {
+		t.Fatalf("Init did not initialize the dir")
+	}
+	if err := os.RemoveAll(path.Join(dir, ".terraform")); err != nil {
+		t.Fatalf("Error removing .terraform %v", err)
+	}
+	if ok, _, _ := tfExec.Init(context.Background()); !ok {
+		t.Errorf("Expected the result of the first Init")
+	}
+	if _, err := os.Stat(path.Join(dir, ".terraform")); err == nil {
+		t.Errorf("Init ran terraform init twice")
+	}
+}
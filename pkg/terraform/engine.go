@@ -0,0 +1,77 @@
+package terraform
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terraform-tools/terraform-checker/pkg/errors"
+)
+
+// Engine is the executable running the terraform configurations of a TfDir.
+type Engine string
+
+const (
+	EngineTerraform Engine = "terraform"
+	EngineTofu      Engine = "tofu"
+	DefaultEngine          = EngineTerraform
+
+	tofuRegistryHost    = "registry.opentofu.org"
+	tofuVersionFileName = ".opentofu-version"
+)
+
+// Engines returns the names of the supported engines.
+func Engines() []string {
+	return []string{string(EngineTerraform), string(EngineTofu)}
+}
+
+func (e Engine) Validate() error {
+	switch e {
+	case EngineTerraform, EngineTofu:
+		return nil
+	default:
+		return errors.EngineNotValidError(fmt.Sprintf("engine %s is not one of %s", e, strings.Join(Engines(), ", ")))
+	}
+}
+
+// Binary returns the name of the executable of the engine.
+func (e Engine) Binary() string {
+	if e == EngineTofu {
+		return "tofu"
+	}
+	return terraformPath
+}
+
+// registryHost returns the registry of the providers given without hostname.
+func (e Engine) registryHost() string {
+	if e == EngineTofu {
+		return tofuRegistryHost
+	}
+	return tfDefaultRegistryHost
+}
+
+// versionFileName returns the name of the file pinning the version of the engine,
+// as used by tfenv and tofuenv.
+func (e Engine) versionFileName() string {
+	if e == EngineTofu {
+		return tofuVersionFileName
+	}
+	return tfVersionFileName
+}
+
+// FindEngine returns the engine of dir, set by the closest .tf-checker file of dir or its parents
+// up to the repository root, so that it can be set for a whole repo at its root.
+func FindEngine(dir string) Engine {
+	for _, current := range repoDirs(dir) {
+		configPath := filepath.Join(current, tfDirConfigName)
+		if engine := Engine(parseTfDirConfig(configPath).Engine); engine != "" {
+			if err := engine.Validate(); err != nil {
+				log.Error().Err(err).Msgf("using engine %s instead of the one set in %s", DefaultEngine, configPath)
+				return DefaultEngine
+			}
+			return engine
+		}
+	}
+	return DefaultEngine
+}
@@ -0,0 +1,109 @@
+package terraform_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+func TestFindEngine(t *testing.T) {
+	t.Parallel()
+
+	repo := t.TempDir()
+	files := map[string]string{
+		".git/HEAD":                "",
+		".tf-checker":              "engine: tofu\n",
+		"stack/main.tf":            "",
+		"legacy/.tf-checker":       "engine: terraform\n",
+		"legacy/main.tf":           "",
+		"invalid/.tf-checker":      "engine: pulumi\n",
+		"invalid/main.tf":          "",
+		"other/.tf-checker":        "lockfile_required: false\n",
+		"other/nested/main.tf":     "",
+		"other/nested/.tf-checker": "enabled: true\n",
+		// A submodule is a repository of its own, the files of its parents are ignored
+		"submodule/.git":    "",
+		"submodule/main.tf": "",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(repo, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		dir      string
+		expected terraform.Engine
+	}{
+		{dir: "stack", expected: terraform.EngineTofu},
+		{dir: "legacy", expected: terraform.EngineTerraform},
+		{dir: "invalid", expected: terraform.DefaultEngine},
+		{dir: "other/nested", expected: terraform.EngineTofu},
+		{dir: "submodule", expected: terraform.DefaultEngine},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.dir, func(t *testing.T) {
+			t.Parallel()
+			if engine := terraform.NewTfDir(filepath.Join(repo, tc.dir)).Engine(); engine != tc.expected {
+				t.Errorf("Expected engine %s, got %s", tc.expected, engine)
+			}
+		})
+	}
+}
+
+func TestFindRequiredProvidersTofu(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := `terraform {
+  required_providers {
+    null = { source = "hashicorp/null" }
+    aws  = { source = "registry.terraform.io/hashicorp/aws" }
+  }
+}`
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	providers, err := terraform.FindRequiredProviders(dir, terraform.EngineTofu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses := map[string]bool{}
+	for _, p := range providers {
+		addresses[p.Address] = true
+	}
+	for _, expected := range []string{"registry.opentofu.org/hashicorp/null", "registry.terraform.io/hashicorp/aws"} {
+		if !addresses[expected] {
+			t.Errorf("Expected provider %s in %v", expected, addresses)
+		}
+	}
+}
+
+func TestFindTerraformBinaryTofu(t *testing.T) {
+	t.Parallel()
+
+	versionsDir := t.TempDir()
+	for _, binary := range []string{"1.5.7/terraform", "1.6.0/tofu"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(versionsDir, binary)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(versionsDir, binary), []byte{}, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	binary, err := terraform.FindTerraformBinary(terraform.EngineTofu, versionsDir, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(versionsDir, "1.6.0", "tofu"); binary != expected {
+		t.Errorf("Expected binary %s, got %s", expected, binary)
+	}
+}
@@ -3,56 +3,177 @@ package terraform
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/terraform-exec/tfexec"
 	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/rs/zerolog/log"
 	"github.com/terraform-linters/tflint/formatter"
+	"github.com/terraform-tools/terraform-checker/pkg/metrics"
+	"github.com/terraform-tools/terraform-checker/pkg/utils"
 )
 
 const (
 	terraformPath               = "terraform"
+	TfLintPath                  = "tflint"
 	tfCheckerSkipInitEnvVarName = "TF_CHECKER_SKIP_INIT"
+	tfLintExitCodeIssuesFound   = 2
+	tfFmtExitCodeUnformatted    = 3
 )
 
-func CheckTfFmt(dir string) (bool, string) {
-	ok, output, tf := tfInit(dir)
-	if !ok {
-		return ok, output
+// ExecConfig configures how terraform is run by the checks and the fixes, it is given to the
+// terraform commands through their environment only.
+type ExecConfig struct {
+	ProviderInstallation ProviderInstallation
+	// VersionsDir holds the versions of the engines to pick from, as <VersionsDir>/<version>/terraform
+	// like tfenv does (or <VersionsDir>/<version>/tofu for OpenTofu). Empty to use the ones of the PATH.
+	VersionsDir string
+	// cliConfigFile installs the providers from the mirrors, it is written by Setup
+	cliConfigFile string
+}
+
+// Setup checks the versions dir and prepares the provider installation, it must be called once
+// before running the checks.
+// Without configured plugin cache dir, the TF_PLUGIN_CACHE_DIR of the environment is used so that
+// it is locked as well.
+func (c *ExecConfig) Setup() error {
+	if err := checkVersionsDir(c.VersionsDir); err != nil {
+		return err
 	}
+	if c.ProviderInstallation.PluginCacheDir == "" {
+		c.ProviderInstallation.PluginCacheDir = os.Getenv(tfPluginCacheDirEnvVarName)
+	}
+	var err error
+	c.cliConfigFile, err = c.ProviderInstallation.setup()
+	return err
+}
 
-	return tfFormat(tf)
+// env returns the variables to add to the environment of the terraform commands.
+func (c ExecConfig) env() map[string]string {
+	env := map[string]string{}
+	if c.ProviderInstallation.PluginCacheDir != "" {
+		env[tfPluginCacheDirEnvVarName] = c.ProviderInstallation.PluginCacheDir
+	}
+	if c.cliConfigFile != "" {
+		env[tfCLIConfigFileEnvVarName] = c.cliConfigFile
+	}
+	return env
 }
 
-func CheckTfValidate(dir string) (bool, string, *tfjson.ValidateOutput) {
-	if ok, output, _ := tfInit(dir); !ok {
-		return ok, output, nil
+// environ returns the environment of the terraform commands, the one of the process with env.
+func (c ExecConfig) environ() []string {
+	environ := os.Environ()
+	for k, v := range c.env() {
+		environ = append(environ, k+"="+v)
+	}
+	return environ
+}
+
+// TfExec runs terraform in a dir for all of its checks: terraform init is run at most
+// once, and only if one of the checks needs it.
+type TfExec struct {
+	dir        string
+	engine     Engine
+	config     ExecConfig
+	execPath   string
+	tf         *tfexec.Terraform
+	err        error
+	initOnce   sync.Once
+	initOk     bool
+	initOutput string
+	// initTimedOut is true if the context of the check running terraform init was done before its end
+	initTimedOut bool
+}
+
+func NewTfExec(dir string, config ExecConfig) *TfExec {
+	e := &TfExec{dir: dir, engine: FindEngine(dir), config: config}
+	if e.execPath, e.err = FindTerraformBinary(e.engine, config.VersionsDir, dir); e.err != nil {
+		log.Error().Err(e.err).Msgf("error selecting %s version for %s", e.engine, dir)
+		return e
+	}
+	if e.tf, e.err = tfexec.NewTerraform(dir, e.execPath); e.err != nil {
+		log.Error().Err(e.err).Msg("error creating Terraform object")
+		return e
 	}
+	if e.err = e.tf.SetEnv(config.env()); e.err != nil {
+		log.Error().Err(e.err).Msg("error setting the environment of terraform")
+	}
+	return e
+}
 
-	ok, output := tfValidate(dir, false)
-	_, outputJSON := tfValidate(dir, true)
+func (e *TfExec) Dir() string {
+	return e.dir
+}
 
-	var outJSON tfjson.ValidateOutput
-	if err := json.Unmarshal([]byte(outputJSON), &outJSON); err != nil {
-		log.Error().Err(err).Msg("error unmarshalling terraform validate output")
+func (e *TfExec) Engine() Engine {
+	return e.engine
+}
+
+// command returns the command running the engine of the dir with args, in the dir.
+func (e *TfExec) command(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := utils.CommandContext(ctx, e.execPath, args...) // #nosec
+	cmd.Dir = e.dir
+	cmd.Env = e.config.environ()
+	return cmd
+}
+
+// Terraform returns terraform for the dir, without initializing it.
+func (e *TfExec) Terraform() (*tfexec.Terraform, error) {
+	return e.tf, e.err
+}
+
+// Init runs terraform init on the first call, the following ones return its result.
+func (e *TfExec) Init(ctx context.Context) (bool, string, *tfexec.Terraform) {
+	e.initOnce.Do(func() {
+		e.initOk, e.initOutput = e.init(ctx)
+		e.initTimedOut = !e.initOk && errors.Is(ctx.Err(), context.DeadlineExceeded)
+		if !e.initOk {
+			metrics.TerraformInitFailed()
+		}
+	})
+	if !e.initOk {
+		return false, e.initOutput, nil
+	}
+	return true, e.initOutput, e.tf
+}
+
+func CheckTfFmt(ctx context.Context, e *TfExec) (bool, string) {
+	if _, err := e.Terraform(); err != nil {
+		return false, err.Error()
+	}
+
+	return e.format(ctx)
+}
+
+func CheckTfValidate(ctx context.Context, e *TfExec) (bool, string, *tfjson.ValidateOutput) {
+	ok, output, _ := e.Init(ctx)
+	if !ok {
+		return ok, output, nil
+	}
+
+	ok, output = e.validate(ctx)
+	outJSON, err := e.validateJSON(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("error running terraform validate")
 		return false, output, nil
 	}
 
-	return ok, output, &outJSON
+	return ok, output, outJSON
 }
 
-func CheckTfLint(dir string) (bool, string, *formatter.JSONOutput) {
-	ok, output, _ := tfInit(dir)
+func CheckTfLint(ctx context.Context, e *TfExec) (bool, string, *formatter.JSONOutput) {
+	ok, output, _ := e.Init(ctx)
 	if !ok {
 		return ok, output, nil
 	}
 
-	ok, out := tfLint(dir, "default")
-	_, outJSONStr := tfLint(dir, "json")
+	ok, out := tfLint(ctx, e.Dir(), "default")
+	_, outJSONStr := tfLint(ctx, e.Dir(), "json")
 
 	var outJSON formatter.JSONOutput
 	if err := json.Unmarshal([]byte(outJSONStr), &outJSON); err != nil {
@@ -63,62 +184,163 @@ func CheckTfLint(dir string) (bool, string, *formatter.JSONOutput) {
 	return ok, out, &outJSON
 }
 
-func tfInit(dir string) (bool, string, *tfexec.Terraform) {
-	workingDir := dir
-	tf, err := tfexec.NewTerraform(workingDir, terraformPath)
-	if err != nil {
-		log.Error().Err(err).Msg("error creating Terraform object")
-		return false, "", nil
+// init runs terraform init in the dir. Like the other commands of the checks, the engine is run
+// directly instead of through terraform-exec, which only kills the engine and not the providers it
+// started when ctx is done.
+func (e *TfExec) init(ctx context.Context) (bool, string) {
+	if e.err != nil {
+		return false, e.err.Error()
 	}
 
 	if value, present := os.LookupEnv(tfCheckerSkipInitEnvVarName); present && value == "true" {
-		return true, "", tf
+		return true, ""
 	}
 
-	err = tf.Init(context.Background(), tfexec.Upgrade(true), tfexec.Backend(false))
+	unlock, err := e.config.ProviderInstallation.lockPluginCache(ctx)
 	if err != nil {
+		log.Error().Err(err).Msg("error waiting for the terraform plugin cache")
+		return false, err.Error()
+	}
+	defer unlock()
+	cmd := e.command(ctx, "init", "-no-color", "-input=false", "-upgrade", "-backend=false")
+	if out, err := cmd.CombinedOutput(); err != nil {
 		log.Error().Err(err).Msg("error running terraform init")
-		return false, err.Error(), nil
+		return false, fmt.Sprintf("%s\n%s", err, out)
 	}
 
-	return true, "", tf
+	return true, ""
 }
 
-func tfValidate(dir string, json bool) (bool, string) {
-	args := []string{"validate", "-no-color"}
-	if json {
-		args = append(args, "-json")
-	}
-	cmd := exec.Command("terraform", args...) // #nosec
-	cmd.Dir = dir
-	out, err := cmd.CombinedOutput()
+func (e *TfExec) validate(ctx context.Context) (bool, string) {
+	out, err := e.command(ctx, "validate", "-no-color").CombinedOutput()
 	return err == nil, string(out)
 }
 
-func tfFormat(tf *tfexec.Terraform) (bool, string) {
-	ok, files, err := tf.FormatCheck(context.Background(), &tfexec.RecursiveOption{})
-	if err != nil {
+// validateJSON runs validate -json. terraform-exec is not used as it checks the output against the
+// terraform JSON format versions, which OpenTofu versions on its own.
+func (e *TfExec) validateJSON(ctx context.Context) (*tfjson.ValidateOutput, error) {
+	out, err := e.command(ctx, "validate", "-no-color", "-json").Output()
+
+	// terraform and tofu exit with 1 when the configuration is invalid
+	var exitErr *exec.ExitError
+	if err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() != 1) {
+		return nil, err
+	}
+	return parseValidateOutput(out)
+}
+
+// parseValidateOutput decodes the validate JSON output of OpenTofu, or of the engine run by terragrunt,
+// without checking its format version, and computes the counts that are not reported.
+func parseValidateOutput(out []byte) (*tfjson.ValidateOutput, error) {
+	// The format version is checked by the UnmarshalJSON of tfjson.ValidateOutput, not by this type
+	type validateOutput tfjson.ValidateOutput
+	var v validateOutput
+	if err := json.Unmarshal(out, &v); err != nil {
+		return nil, err
+	}
+
+	if v.ErrorCount == 0 && v.WarningCount == 0 {
+		for _, diag := range v.Diagnostics {
+			switch diag.Severity {
+			case tfjson.DiagnosticSeverityError:
+				v.ErrorCount++
+			case tfjson.DiagnosticSeverityWarning:
+				v.WarningCount++
+			case tfjson.DiagnosticSeverityUnknown:
+			}
+		}
+	}
+	result := tfjson.ValidateOutput(v)
+	return &result, nil
+}
+
+// FormatValidateOutput renders the diagnostics of validate -json, close to the output of validate
+// without -json, so that validate does not need to be run twice.
+func FormatValidateOutput(v *tfjson.ValidateOutput) string {
+	var b strings.Builder
+	for _, diag := range v.Diagnostics {
+		severity := string(diag.Severity)
+		if severity != "" {
+			severity = strings.ToUpper(severity[:1]) + severity[1:]
+		}
+		fmt.Fprintf(&b, "%s: %s\n", severity, diag.Summary)
+		if diag.Range != nil {
+			fmt.Fprintf(&b, "\n  on %s line %d:\n", diag.Range.Filename, diag.Range.Start.Line)
+		}
+		if diag.Detail != "" {
+			fmt.Fprintf(&b, "\n%s\n", diag.Detail)
+		}
+		b.WriteString("\n")
+	}
+	switch {
+	case !v.Valid:
+	case v.WarningCount > 0:
+		b.WriteString("Success! The configuration is valid, but there were some validation warnings as shown above.\n")
+	default:
+		b.WriteString("Success! The configuration is valid.\n")
+	}
+	return b.String()
+}
+
+func (e *TfExec) format(ctx context.Context) (bool, string) {
+	out, err := e.command(ctx, "fmt", "-no-color", "-write=false", "-list=true", "-diff=false", "-check=true", "-recursive").Output()
+	if err == nil {
+		return true, ""
+	}
+
+	// The unformatted files are listed with the exit code 3
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != tfFmtExitCodeUnformatted {
 		log.Error().Err(err).Msg("error running terraform fmt check")
-		return false, ""
+		if exitErr != nil {
+			return false, fmt.Sprintf("%s\n%s", err, exitErr.Stderr)
+		}
+		return false, err.Error()
 	}
-	if !ok {
-		return false, "Your terraform formatting is wrong for the following files:\n" + strings.Join(
-			files,
-			"\n",
-		) + "\nplease run `terraform fmt -recursive` in the right dir or launch the `Trigger tf fmt` action ⬆️⬆️⬆️" + "\n\n" + "more info [here](https://www.terraform.io/docs/cli/commands/fmt.html)"
+	files := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
 	}
-	return true, ""
+	return false, "Your terraform formatting is wrong for the following files:\n" + strings.Join(
+		files,
+		"\n",
+	) + "\nplease run `terraform fmt -recursive` in the right dir or launch the `Trigger tf fmt` action ⬆️⬆️⬆️" + "\n\n" + "more info [here](https://www.terraform.io/docs/cli/commands/fmt.html)"
 }
 
-func tfLint(dir, format string) (bool, string) {
-	cmd := exec.Command("tflint", []string{fmt.Sprintf("-f=%s", format)}...) // #nosec
+func tfLint(ctx context.Context, dir, format string) (bool, string) {
+	cmd := utils.CommandContext(ctx, TfLintPath, []string{fmt.Sprintf("-f=%s", format)}...) // #nosec
 	cmd.Dir = dir
 	out, err := cmd.CombinedOutput()
 	return err == nil, string(out)
 }
 
-func tfLintInit() (bool, string) {
-	cmd := exec.Command("tflint", []string{"--init"}...) // #nosec
+// tfLintFix runs tflint --fix, issues that cannot be fixed are not considered as a failure.
+func tfLintFix(ctx context.Context, dir string) (bool, string) {
+	cmd := utils.CommandContext(ctx, TfLintPath, []string{"--fix"}...) // #nosec
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == tfLintExitCodeIssuesFound {
+		return true, string(out)
+	}
+	return err == nil, string(out)
+}
+
+// BinaryVersion returns the version of binary, as the first line of the output of binary --version.
+func BinaryVersion(ctx context.Context, binary string) (string, error) {
+	out, err := utils.CommandContext(ctx, binary, "--version").Output() // #nosec
+	if err != nil {
+		return "", err
+	}
+	version, _, _ := strings.Cut(string(out), "\n")
+	return strings.TrimSpace(version), nil
+}
+
+func tfLintInit(ctx context.Context) (bool, string) {
+	cmd := utils.CommandContext(ctx, TfLintPath, []string{"--init"}...) // #nosec
 	out, err := cmd.CombinedOutput()
 	return err == nil, string(out)
 }
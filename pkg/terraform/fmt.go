@@ -2,23 +2,39 @@ package terraform
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/rs/zerolog/log"
-
-	"github.com/hashicorp/terraform-exec/tfexec"
 )
 
-func FixFmt(cloneDir string) error {
+func FixFmt(ctx context.Context, config ExecConfig, cloneDir string) error {
 	for _, tfDir := range FindAllTfDir(cloneDir) {
-		log.Info().Msgf("Executing action fmt on tfDir: %s", tfDir.Path())
-		tf, err := tfexec.NewTerraform(tfDir.Path(), terraformPath)
-		if err != nil {
+		if err := fixTfDirFmt(ctx, config, tfDir, ""); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		if err := tf.FormatWrite(context.TODO()); err != nil {
-			return err
-		}
+func fixTfDirFmt(ctx context.Context, config ExecConfig, tfDir *TfDir, _ string) error {
+	log.Info().Msgf("Executing action fmt on tfDir: %s", tfDir.Path())
+	e := NewTfExec(tfDir.Path(), config)
+	if tfDir.IsTerragrunt() {
+		return fixTerragruntFmt(ctx, e)
+	}
+	if _, err := e.Terraform(); err != nil {
+		return err
+	}
+	if out, err := e.command(ctx, "fmt", "-no-color", "-write=true", "-list=false", "-diff=false").CombinedOutput(); err != nil {
+		return fmt.Errorf("%w\n%s", err, out)
+	}
+	return nil
+}
+
+func fixTfDirTfLint(ctx context.Context, _ ExecConfig, tfDir *TfDir, _ string) error {
+	log.Info().Msgf("Executing action tflint on tfDir: %s", tfDir.Path())
+	if ok, out := tfLintFix(ctx, tfDir.Path()); !ok {
+		return fmt.Errorf("tflint --fix failed: %s", out)
 	}
 	return nil
 }
@@ -0,0 +1,585 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/rs/zerolog/log"
+	"github.com/terraform-tools/terraform-checker/pkg/utils"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	tfLockfileName                = ".terraform.lock.hcl"
+	tfLockfilePlatformsEnvVarName = "TF_CHECKER_LOCKFILE_PLATFORMS"
+	tfDefaultRegistryHost         = "registry.terraform.io"
+	tfDefaultProviderNamespace    = "hashicorp"
+	tfBuiltinProviderHost         = "terraform.io"
+	tfPackageHashPrefix           = "h1:"
+)
+
+// providerHashes caches the package hashes computed by terraform providers lock, by platform,
+// address and version of provider, as they never change. Each provider package is downloaded at
+// most once by the process instead of on each check.
+var providerHashes sync.Map //nolint:gochecknoglobals // shared by the checks of every event
+
+// DefaultLockfilePlatforms returns the platforms every lock file must have hashes for,
+// unless overridden in a .tf-checker file.
+func DefaultLockfilePlatforms() []string {
+	if value, present := os.LookupEnv(tfLockfilePlatformsEnvVarName); present && value != "" {
+		return strings.Split(value, ",")
+	}
+	return []string{"linux_amd64", "darwin_arm64"}
+}
+
+// LockedProvider is a provider block of a .terraform.lock.hcl file.
+type LockedProvider struct {
+	Address     string
+	Version     string
+	Constraints string
+	Hashes      []string
+	Range       hcl.Range
+}
+
+// HasHash returns true if the given hash is recorded for the provider.
+func (p *LockedProvider) HasHash(hash string) bool {
+	for _, h := range p.Hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiredProvider is an entry of a terraform required_providers block.
+type RequiredProvider struct {
+	Name    string
+	Address string
+	Version string
+	Range   hcl.Range
+}
+
+// LockfileIssue is a problem found on a lock file, located on the block it relates to.
+type LockfileIssue struct {
+	Summary string
+	Detail  string
+	Range   hcl.Range
+	// Unverified issues tell that the lock file could not be fully checked, not that it is wrong
+	Unverified bool
+}
+
+type lockfileProviderBlock struct {
+	Version     string   `hcl:"version"`
+	Constraints string   `hcl:"constraints,optional"`
+	Hashes      []string `hcl:"hashes,optional"`
+	Remain      hcl.Body `hcl:",remain"`
+}
+
+// ParseTfLockfile parses the content of a .terraform.lock.hcl file.
+func ParseTfLockfile(src []byte, filename string) ([]*LockedProvider, error) {
+	file, diags := hclparse.NewParser().ParseHCL(src, filename)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	content, _, diags := file.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "provider", LabelNames: []string{"address"}}},
+	})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	providers := make([]*LockedProvider, 0, len(content.Blocks))
+	for _, block := range content.Blocks {
+		var providerBlock lockfileProviderBlock
+		if diags := gohcl.DecodeBody(block.Body, nil, &providerBlock); diags.HasErrors() {
+			return nil, diags
+		}
+		providers = append(providers, &LockedProvider{
+			Address:     strings.ToLower(block.Labels[0]),
+			Version:     providerBlock.Version,
+			Constraints: providerBlock.Constraints,
+			Hashes:      providerBlock.Hashes,
+			Range:       block.DefRange,
+		})
+	}
+	return providers, nil
+}
+
+// FindRequiredProviders returns the providers declared in the required_providers blocks
+// of the terraform files of a directory, providers without hostname come from the registry of engine.
+func FindRequiredProviders(dir string, engine Engine) ([]*RequiredProvider, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	providers := []*RequiredProvider{}
+	for _, f := range files {
+		file, diags := parser.ParseHCLFile(f)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		content, _, _ := file.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{{Type: "terraform"}},
+		})
+		for _, tfBlock := range content.Blocks {
+			tfContent, _, _ := tfBlock.Body.PartialContent(&hcl.BodySchema{
+				Blocks: []hcl.BlockHeaderSchema{{Type: "required_providers"}},
+			})
+			for _, rpBlock := range tfContent.Blocks {
+				attrs, diags := rpBlock.Body.JustAttributes()
+				if diags.HasErrors() {
+					return nil, diags
+				}
+				for _, attr := range attrs {
+					if p := newRequiredProvider(attr, engine); p != nil {
+						providers = append(providers, p)
+					}
+				}
+			}
+		}
+	}
+	return providers, nil
+}
+
+func newRequiredProvider(attr *hcl.Attribute, engine Engine) *RequiredProvider {
+	source := ""
+	p := &RequiredProvider{
+		Name:  attr.Name,
+		Range: attr.Range,
+	}
+
+	switch expr := attr.Expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		for _, item := range expr.Items {
+			// configuration_aliases holds references, only literal strings are of interest
+			val, diags := item.ValueExpr.Value(nil)
+			if diags.HasErrors() || val.IsNull() || val.Type() != cty.String {
+				continue
+			}
+			switch objectKey(item.KeyExpr) {
+			case "source":
+				source = val.AsString()
+			case "version":
+				p.Version = val.AsString()
+			}
+		}
+	default:
+		// Legacy syntax: name = "version constraint"
+		if val, diags := attr.Expr.Value(nil); !diags.HasErrors() && !val.IsNull() && val.Type() == cty.String {
+			p.Version = val.AsString()
+		}
+	}
+
+	p.Address = providerAddress(engine, p.Name, source)
+	if strings.HasPrefix(p.Address, tfBuiltinProviderHost+"/") {
+		return nil
+	}
+	return p
+}
+
+func objectKey(expr hcl.Expression) string {
+	if key := hcl.ExprAsKeyword(expr); key != "" {
+		return key
+	}
+	if val, diags := expr.Value(nil); !diags.HasErrors() && !val.IsNull() && val.Type() == cty.String {
+		return val.AsString()
+	}
+	return ""
+}
+
+// providerAddress returns the fully qualified address of a provider, as written in lock files.
+func providerAddress(engine Engine, name, source string) string {
+	if source == "" {
+		source = name
+	}
+	parts := strings.Split(strings.ToLower(source), "/")
+	switch len(parts) {
+	case 1:
+		return strings.Join([]string{engine.registryHost(), tfDefaultProviderNamespace, parts[0]}, "/")
+	case 2: //nolint:gomnd // namespace/type
+		return strings.Join([]string{engine.registryHost(), parts[0], parts[1]}, "/")
+	default:
+		return strings.Join(parts, "/")
+	}
+}
+
+// CheckTfLockfile checks that the lock file content is in sync with the required_providers of dir,
+// and that every locked provider has hashes for all of the platforms.
+// A nil lockfile means that the dir of e does not have any lock file.
+// The hashes are computed by terraform providers lock for each platform, from the mirror given in
+// opts, or else from the registries. It downloads the
+// provider packages, ignoring the plugin cache, so the hashes are cached by providerHashes.
+// When they cannot be computed, the check fails with an Unverified issue only.
+func CheckTfLockfile(ctx context.Context, e *TfExec, lockfile []byte, platforms []string, required bool, opts ...tfexec.ProvidersLockOption) (bool, string, []*LockfileIssue) {
+	dir, engine := e.Dir(), e.Engine()
+	requiredProviders, err := FindRequiredProviders(dir, engine)
+	if err != nil {
+		log.Error().Err(err).Msg("error reading required_providers")
+		return false, err.Error(), nil
+	}
+
+	var issues []*LockfileIssue
+	if lockfile == nil {
+		if !required {
+			return true, "", nil
+		}
+		for _, p := range requiredProviders {
+			issues = append(issues, &LockfileIssue{
+				Summary: "Provider not locked",
+				Detail:  fmt.Sprintf("Provider %s is not locked, %s file is missing", p.Address, tfLockfileName),
+				Range:   p.Range,
+			})
+		}
+	} else {
+		lockedProviders, err := ParseTfLockfile(lockfile, filepath.Join(dir, tfLockfileName))
+		if err != nil {
+			log.Error().Err(err).Msg("error parsing lock file")
+			return false, err.Error(), nil
+		}
+
+		issues = lockfileSyncIssues(requiredProviders, lockedProviders)
+		// Hashes can only be computed once the lock file is in sync
+		if len(issues) == 0 {
+			if _, err := e.Terraform(); err != nil {
+				return false, err.Error(), nil
+			}
+			issues = lockfilePlatformIssues(ctx, e.execPath, filepath.Join(dir, tfLockfileName), lockedProviders, platforms, opts)
+		}
+	}
+
+	if len(issues) == 0 {
+		return true, "", nil
+	}
+	return false, lockfileOutput(issues, engine, platforms), issues
+}
+
+func lockfileSyncIssues(requiredProviders []*RequiredProvider, lockedProviders []*LockedProvider) (issues []*LockfileIssue) {
+	locked := make(map[string]*LockedProvider, len(lockedProviders))
+	for _, p := range lockedProviders {
+		locked[p.Address] = p
+	}
+
+	for _, rp := range requiredProviders {
+		lp, ok := locked[rp.Address]
+		if !ok {
+			issues = append(issues, &LockfileIssue{
+				Summary: "Provider not locked",
+				Detail:  fmt.Sprintf("Provider %s is required but missing from %s", rp.Address, tfLockfileName),
+				Range:   rp.Range,
+			})
+			continue
+		}
+
+		if !lockedVersionMatches(rp, lp) {
+			issues = append(issues, &LockfileIssue{
+				Summary: "Locked provider version out of sync",
+				Detail:  fmt.Sprintf("Locked version %s of provider %s does not match constraint %s", lp.Version, lp.Address, rp.Version),
+				Range:   lp.Range,
+			})
+		}
+	}
+	return issues
+}
+
+// lockedVersionMatches returns false if the locked version does not satisfy the version
+// constraint of the required provider.
+func lockedVersionMatches(rp *RequiredProvider, lp *LockedProvider) bool {
+	if rp.Version == "" {
+		return true
+	}
+	constraints, err := version.NewConstraint(rp.Version)
+	if err != nil {
+		log.Debug().Err(err).Msgf("skipping invalid version constraint %s of provider %s", rp.Version, rp.Address)
+		return true
+	}
+	v, err := version.NewVersion(lp.Version)
+	return err == nil && constraints.Check(v)
+}
+
+func lockfilePlatformIssues(ctx context.Context, execPath, lockfilePath string, lockedProviders []*LockedProvider, platforms []string, opts []tfexec.ProvidersLockOption) []*LockfileIssue {
+	if len(lockedProviders) == 0 || len(platforms) == 0 {
+		return nil
+	}
+
+	var issues []*LockfileIssue
+	missingPlatforms := make(map[string][]string, len(lockedProviders))
+	for _, platform := range platforms {
+		hashes, err := platformHashes(ctx, execPath, lockedProviders, platform, opts)
+		if err != nil {
+			log.Error().Err(err).Msgf("error computing provider hashes for platform %s", platform)
+			issues = append(issues, &LockfileIssue{
+				Summary:    "Provider hashes not verified",
+				Detail:     fmt.Sprintf("Hashes of platform %s could not be computed: %s", platform, strings.TrimSpace(err.Error())),
+				Range:      hcl.Range{Filename: lockfilePath, Start: hcl.InitialPos, End: hcl.InitialPos},
+				Unverified: true,
+			})
+			continue
+		}
+
+		for _, lp := range lockedProviders {
+			for _, hash := range hashes[lp.Address] {
+				if !lp.HasHash(hash) {
+					missingPlatforms[lp.Address] = append(missingPlatforms[lp.Address], platform)
+					break
+				}
+			}
+		}
+	}
+
+	for _, lp := range lockedProviders {
+		if missing, ok := missingPlatforms[lp.Address]; ok {
+			issues = append(issues, &LockfileIssue{
+				Summary: "Missing provider hashes",
+				Detail:  fmt.Sprintf("Provider %s %s has no hash for platforms: %s", lp.Address, lp.Version, strings.Join(missing, ", ")),
+				Range:   lp.Range,
+			})
+		}
+	}
+	return issues
+}
+
+// platformHashes returns the package hashes of platform of the locked providers by address, taken
+// from providerHashes or computed for the providers missing from it.
+func platformHashes(ctx context.Context, execPath string, lockedProviders []*LockedProvider, platform string, opts []tfexec.ProvidersLockOption) (map[string][]string, error) {
+	key := func(p *LockedProvider) string {
+		return strings.Join([]string{platform, p.Address, p.Version}, " ")
+	}
+
+	hashes := make(map[string][]string, len(lockedProviders))
+	uncached := []*LockedProvider{}
+	for _, lp := range lockedProviders {
+		if cached, ok := providerHashes.Load(key(lp)); ok {
+			hashes[lp.Address] = cached.([]string) //nolint:forcetypeassert // only []string are stored
+		} else {
+			uncached = append(uncached, lp)
+		}
+	}
+	if len(uncached) == 0 {
+		return hashes, nil
+	}
+
+	platformProviders, err := lockProvidersForPlatform(ctx, execPath, uncached, platform, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, lp := range uncached {
+		for _, pp := range platformProviders {
+			if pp.Address != lp.Address {
+				continue
+			}
+			// The zh: hashes of the registries are the ones of every platform, only h1: ones are of platform
+			packageHashes := []string{}
+			for _, hash := range pp.Hashes {
+				if strings.HasPrefix(hash, tfPackageHashPrefix) {
+					packageHashes = append(packageHashes, hash)
+				}
+			}
+			providerHashes.Store(key(lp), packageHashes)
+			hashes[lp.Address] = packageHashes
+		}
+	}
+	return hashes, nil
+}
+
+// lockProvidersForPlatform runs terraform providers lock for a single platform in a scratch
+// directory holding a configuration pinning the locked providers, without lock file so that only
+// the hashes of platform are recorded.
+// This avoids touching the checked directory and needing its modules to be installed.
+func lockProvidersForPlatform(ctx context.Context, execPath string, lockedProviders []*LockedProvider, platform string, opts []tfexec.ProvidersLockOption) ([]*LockedProvider, error) {
+	dir, err := os.MkdirTemp("", "tf-checker-lock")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "versions.tf"), pinnedProvidersConfig(lockedProviders), 0o600); err != nil { //nolint:gomnd
+		return nil, err
+	}
+
+	tf, err := tfexec.NewTerraform(dir, execPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := tf.ProvidersLock(ctx, append([]tfexec.ProvidersLockOption{tfexec.Platform(platform)}, opts...)...); err != nil {
+		return nil, err
+	}
+
+	newLockfile, err := os.ReadFile(filepath.Join(dir, tfLockfileName))
+	if err != nil {
+		return nil, err
+	}
+	return ParseTfLockfile(newLockfile, tfLockfileName)
+}
+
+func pinnedProvidersConfig(lockedProviders []*LockedProvider) []byte {
+	var b strings.Builder
+	b.WriteString("terraform {\n  required_providers {\n")
+	for i, p := range lockedProviders {
+		fmt.Fprintf(&b, "    p%d = {\n      source  = %q\n      version = %q\n    }\n", i, p.Address, "= "+p.Version)
+	}
+	b.WriteString("  }\n}\n")
+	return []byte(b.String())
+}
+
+// lockfileUnverified returns true if issues only tell that the lock file could not be fully checked.
+func lockfileUnverified(issues []*LockfileIssue) bool {
+	for _, issue := range issues {
+		if !issue.Unverified {
+			return false
+		}
+	}
+	return len(issues) > 0
+}
+
+func lockfileOutput(issues []*LockfileIssue, engine Engine, platforms []string) string {
+	lines := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		lines = append(lines, fmt.Sprintf("- %s: %s", issue.Summary, issue.Detail))
+	}
+	if lockfileUnverified(issues) {
+		return fmt.Sprintf("The provider hashes of your %s file could not be verified:\n", tfLockfileName) + strings.Join(lines, "\n")
+	}
+
+	platformArgs := make([]string, 0, len(platforms))
+	for _, p := range platforms {
+		platformArgs = append(platformArgs, "-platform="+p)
+	}
+
+	docURL := "https://developer.hashicorp.com/terraform/cli/commands/providers/lock"
+	if engine == EngineTofu {
+		docURL = "https://opentofu.org/docs/cli/commands/providers/lock/"
+	}
+	return fmt.Sprintf("Your %s file is missing or out of date:\n", tfLockfileName) + strings.Join(
+		lines,
+		"\n",
+	) + fmt.Sprintf("\nplease run `%s providers lock %s` in the right dir or launch the `Fix lock file` action ⬆️⬆️⬆️", engine.Binary(), strings.Join(platformArgs, " ")) +
+		"\n\n" + fmt.Sprintf("more info [here](%s)", docURL)
+}
+
+// FixLockfile runs terraform providers lock for the configured platforms in the terraform
+// directories of relDirs that need a lock file, Terragrunt units aside as terragrunt manages their lock file.
+// relDirs are relative to cloneDir, the root dir being empty, so that only the dirs whose lockfile
+// check failed are locked again.
+// Locked versions that do not match the required_providers constraints anymore are dropped first,
+// so that terraform can select new ones.
+func FixLockfile(ctx context.Context, config ExecConfig, cloneDir string, relDirs []string) error {
+	for _, tfDir := range FindAllTfDir(cloneDir) {
+		if !tfDir.IsEnabled() || tfDir.IsTerragrunt() {
+			continue
+		}
+		relDir, err := filepath.Rel(cloneDir, tfDir.Path())
+		if err != nil {
+			return err
+		}
+		if relDir == "." {
+			relDir = ""
+		}
+		if !utils.StrInSlice(relDirs, relDir) {
+			continue
+		}
+		if err := fixTfDirLockfile(ctx, config, tfDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fixTfDirLockfile(ctx context.Context, config ExecConfig, tfDir *TfDir) error {
+	lockfilePath := filepath.Join(tfDir.Path(), tfLockfileName)
+	requiredProviders, err := FindRequiredProviders(tfDir.Path(), tfDir.Engine())
+	if err != nil {
+		return err
+	}
+	_, statErr := os.Stat(lockfilePath)
+	hasLockfile := statErr == nil
+	if !hasLockfile && (!tfDir.IsLockfileRequired() || len(requiredProviders) == 0) {
+		return nil
+	}
+
+	log.Info().Msgf("Executing action lockfile on tfDir: %s", tfDir.Path())
+	if hasLockfile {
+		if err := pruneOutOfSyncLocks(lockfilePath, requiredProviders); err != nil {
+			return err
+		}
+	}
+
+	return lockTfDir(ctx, config, tfDir)
+}
+
+func lockTfDir(ctx context.Context, config ExecConfig, tfDir *TfDir) error {
+	tf, err := NewTfExec(tfDir.Path(), config).Terraform()
+	if err != nil {
+		return err
+	}
+
+	// Modules must be installed for terraform to load the configuration,
+	// they are removed afterwards so that they don't end up in the fix commit
+	tfDataDir := filepath.Join(tfDir.Path(), ".terraform")
+	if _, err := os.Stat(tfDataDir); errors.Is(err, fs.ErrNotExist) {
+		defer os.RemoveAll(tfDataDir)
+	}
+	if err := tf.Get(ctx); err != nil {
+		return err
+	}
+
+	opts := config.ProviderInstallation.providersLockOptions()
+	lockOpts := make([]tfexec.ProvidersLockOption, 0, len(tfDir.LockfilePlatforms())+len(opts))
+	for _, platform := range tfDir.LockfilePlatforms() {
+		lockOpts = append(lockOpts, tfexec.Platform(platform))
+	}
+	return tf.ProvidersLock(ctx, append(lockOpts, opts...)...)
+}
+
+// pruneOutOfSyncLocks removes from a lock file the providers whose locked version
+// does not satisfy the required_providers constraints.
+func pruneOutOfSyncLocks(lockfilePath string, requiredProviders []*RequiredProvider) error {
+	src, err := os.ReadFile(lockfilePath)
+	if err != nil {
+		return err
+	}
+	lockedProviders, err := ParseTfLockfile(src, lockfilePath)
+	if err != nil {
+		return err
+	}
+
+	outOfSync := []string{}
+	for _, rp := range requiredProviders {
+		for _, lp := range lockedProviders {
+			if lp.Address == rp.Address && !lockedVersionMatches(rp, lp) {
+				outOfSync = append(outOfSync, lp.Address)
+			}
+		}
+	}
+	if len(outOfSync) == 0 {
+		return nil
+	}
+
+	file, diags := hclwrite.ParseConfig(src, lockfilePath, hcl.InitialPos)
+	if diags.HasErrors() {
+		return diags
+	}
+	for _, block := range file.Body().Blocks() {
+		if labels := block.Labels(); block.Type() == "provider" && len(labels) == 1 && utils.StrInSlice(outOfSync, strings.ToLower(labels[0])) {
+			log.Debug().Msgf("Removing out of sync provider %s from %s", labels[0], lockfilePath)
+			file.Body().RemoveBlock(block)
+		}
+	}
+	return os.WriteFile(lockfilePath, file.Bytes(), 0o600) //nolint:gomnd
+}
@@ -0,0 +1,265 @@
+package terraform_test
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+func TestParseTfLockfile(t *testing.T) {
+	t.Parallel()
+	testDir, _ := filepath.Abs("../../test")
+
+	lockfilePath := path.Join(testDir, "terraform_lockfile_out_of_sync", ".terraform.lock.hcl")
+	data, err := os.ReadFile(lockfilePath)
+	if err != nil {
+		t.Fatalf("Error reading lock file %v", err)
+	}
+
+	providers, err := terraform.ParseTfLockfile(data, lockfilePath)
+	if err != nil {
+		t.Fatalf("ParseTfLockfile failed: %v", err)
+	}
+	if len(providers) != 1 {
+		t.Fatalf("Expected 1 provider, got %v", len(providers))
+	}
+	if p := providers[0]; p.Address != "registry.terraform.io/hashicorp/null" || p.Version != "2.1.2" || len(p.Hashes) != 2 || p.Range.Start.Line != 4 {
+		t.Errorf("Unexpected provider parsed: %+v", p)
+	}
+}
+
+func TestFindRequiredProviders(t *testing.T) {
+	t.Parallel()
+	testDir, _ := filepath.Abs("../../test")
+
+	providers, err := terraform.FindRequiredProviders(path.Join(testDir, "terraform_lockfile_out_of_sync"), terraform.EngineTerraform)
+	if err != nil {
+		t.Fatalf("FindRequiredProviders failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"registry.terraform.io/hashicorp/null":   "~> 3.0",
+		"registry.terraform.io/hashicorp/random": "",
+	}
+	if len(providers) != len(expected) {
+		t.Fatalf("Expected %v providers, got %v", len(expected), len(providers))
+	}
+	for _, p := range providers {
+		if v, ok := expected[p.Address]; !ok || v != p.Version {
+			t.Errorf("Unexpected required provider %+v", p)
+		}
+	}
+}
+
+func TestCheckTfLockfile(t *testing.T) {
+	t.Parallel()
+	testDir, _ := filepath.Abs("../../test")
+
+	testCases := []struct {
+		directory string
+		required  bool
+		output    bool
+		issues    int
+	}{
+		{
+			directory: "terraform_ok",
+			required:  true,
+			output:    true,
+			issues:    0,
+		}, {
+			directory: "terraform_lockfile_missing",
+			required:  true,
+			output:    false,
+			issues:    1,
+		}, {
+			directory: "terraform_lockfile_missing",
+			required:  false,
+			output:    true,
+			issues:    0,
+		}, {
+			directory: "terraform_lockfile_out_of_sync",
+			required:  true,
+			output:    false,
+			issues:    2,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.directory, func(t *testing.T) {
+			t.Parallel()
+			dir := path.Join(testDir, tc.directory)
+			lockfile, err := os.ReadFile(path.Join(dir, ".terraform.lock.hcl"))
+			if err != nil {
+				lockfile = nil
+			}
+
+			ok, msg, issues := terraform.CheckTfLockfile(context.Background(), terraform.NewTfExec(dir, terraform.ExecConfig{}), lockfile, []string{"linux_amd64"}, tc.required)
+			if ok != tc.output || len(issues) != tc.issues {
+				t.Errorf("CheckTfLockfile failed for dir %v, expected %v with %v issues, got %v with %v issues, message %v", tc.directory, tc.output, tc.issues, ok, len(issues), msg)
+			}
+		})
+	}
+}
+
+// newProviderMirror creates a filesystem provider mirror holding a fake package
+// of hashicorp/null for each of the platforms.
+func newProviderMirror(t *testing.T, version string, platforms []string) string {
+	t.Helper()
+	mirror := t.TempDir()
+	providerDir := path.Join(mirror, "registry.terraform.io", "hashicorp", "null")
+	if err := os.MkdirAll(providerDir, 0o755); err != nil {
+		t.Fatalf("Error creating mirror %v", err)
+	}
+
+	for _, platform := range platforms {
+		f, err := os.Create(path.Join(providerDir, "terraform-provider-null_"+version+"_"+platform+".zip"))
+		if err != nil {
+			t.Fatalf("Error creating provider package %v", err)
+		}
+		w := zip.NewWriter(f)
+		bin, err := w.Create("terraform-provider-null_v" + version + "_x5")
+		if err == nil {
+			_, err = bin.Write([]byte(platform))
+		}
+		if err != nil {
+			t.Fatalf("Error writing provider package %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Error writing provider package %v", err)
+		}
+		f.Close()
+	}
+	return mirror
+}
+
+func TestCheckTfLockfileMissingPlatformHash(t *testing.T) {
+	t.Parallel()
+	testDir, _ := filepath.Abs("../../test")
+	mirror := newProviderMirror(t, "3.2.1", []string{"linux_amd64", "darwin_arm64"})
+
+	// The lock file is in sync, with the hash of linux_amd64 only
+	dir := t.TempDir()
+	data, err := os.ReadFile(path.Join(testDir, "terraform_lockfile_missing", "main.tf"))
+	if err != nil {
+		t.Fatalf("Error reading fixture %v", err)
+	}
+	if err := os.WriteFile(path.Join(dir, "main.tf"), data, 0o600); err != nil {
+		t.Fatalf("Error writing fixture %v", err)
+	}
+	if err := os.WriteFile(path.Join(dir, ".tf-checker"), []byte("lockfile_required: true\nlockfile_platforms: [linux_amd64]\n"), 0o600); err != nil {
+		t.Fatalf("Error writing fixture %v", err)
+	}
+	if err := terraform.FixLockfile(context.Background(), terraform.ExecConfig{ProviderInstallation: terraform.ProviderInstallation{FilesystemMirror: mirror}}, dir, []string{""}); err != nil {
+		t.Fatalf("FixLockfile failed: %v", err)
+	}
+	lockfile, err := os.ReadFile(path.Join(dir, ".terraform.lock.hcl"))
+	if err != nil {
+		t.Fatalf("Lock file not created: %v", err)
+	}
+
+	platforms := []string{"linux_amd64", "darwin_arm64"}
+	ok, msg, issues := terraform.CheckTfLockfile(context.Background(), terraform.NewTfExec(dir, terraform.ExecConfig{}), lockfile, platforms, true, tfexec.FSMirror(mirror))
+	if ok || len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %v with %v issues, message %v", ok, len(issues), msg)
+	}
+	issue := issues[0]
+	if issue.Summary != "Missing provider hashes" || issue.Detail != "Provider registry.terraform.io/hashicorp/null 3.2.1 has no hash for platforms: darwin_arm64" {
+		t.Errorf("Unexpected issue %s: %s", issue.Summary, issue.Detail)
+	}
+	if filepath.Base(issue.Range.Filename) != ".terraform.lock.hcl" || issue.Range.Start.Line == 0 {
+		t.Errorf("Issue not located on the provider block of the lock file: %+v", issue.Range)
+	}
+
+	if !strings.Contains(msg, "`terraform providers lock -platform=linux_amd64 -platform=darwin_arm64`") {
+		t.Errorf("Expected the providers lock command of the engine in %v", msg)
+	}
+
+	// The hashes are cached, the providers are not downloaded again from the registry
+	if _, _, issues := terraform.CheckTfLockfile(context.Background(), terraform.NewTfExec(dir, terraform.ExecConfig{}), lockfile, platforms, true); len(issues) != 1 || issues[0].Detail != issue.Detail {
+		t.Errorf("Expected the cached hashes to be checked, got %v issues", len(issues))
+	}
+}
+
+func TestCheckTfLockfileUnverifiedHashes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := `terraform {
+  required_providers {
+    null = { source = "hashicorp/null", version = "3.2.0" }
+  }
+}`
+	lockfile := []byte(`provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.0"
+  constraints = "3.2.0"
+  hashes      = ["h1:unknown"]
+}
+`)
+	if err := os.WriteFile(path.Join(dir, "main.tf"), []byte(config), 0o600); err != nil {
+		t.Fatalf("Error writing fixture %v", err)
+	}
+
+	// The mirror cannot be reached, the hashes cannot be computed
+	ok, msg, issues := terraform.CheckTfLockfile(context.Background(), terraform.NewTfExec(dir, terraform.ExecConfig{}), lockfile, []string{"linux_amd64"}, true, tfexec.NetMirror("https://127.0.0.1:1/"))
+	if ok || len(issues) != 1 || !issues[0].Unverified || issues[0].Summary != "Provider hashes not verified" {
+		t.Fatalf("Expected an unverified issue, got %v with %+v", ok, issues)
+	}
+	if !strings.Contains(msg, "could not be verified") || strings.Contains(msg, "providers lock") {
+		t.Errorf("Unexpected message %v", msg)
+	}
+}
+
+func TestFixLockfile(t *testing.T) {
+	t.Parallel()
+	testDir, _ := filepath.Abs("../../test")
+	platforms := []string{"linux_amd64", "darwin_arm64"}
+	mirror := newProviderMirror(t, "3.2.1", platforms)
+
+	dir := t.TempDir()
+	data, err := os.ReadFile(path.Join(testDir, "terraform_lockfile_missing", "main.tf"))
+	if err != nil {
+		t.Fatalf("Error reading fixture %v", err)
+	}
+	// other needs a lock file too, but its lockfile check did not fail
+	for _, d := range []string{dir, path.Join(dir, "other")} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatalf("Error creating fixture %v", err)
+		}
+		if err := os.WriteFile(path.Join(d, "main.tf"), data, 0o600); err != nil {
+			t.Fatalf("Error writing fixture %v", err)
+		}
+		if err := os.WriteFile(path.Join(d, ".tf-checker"), []byte("lockfile_required: true\nlockfile_platforms: [linux_amd64, darwin_arm64]\n"), 0o600); err != nil {
+			t.Fatalf("Error writing fixture %v", err)
+		}
+	}
+
+	if err := terraform.FixLockfile(context.Background(), terraform.ExecConfig{ProviderInstallation: terraform.ProviderInstallation{FilesystemMirror: mirror}}, dir, []string{""}); err != nil {
+		t.Fatalf("FixLockfile failed: %v", err)
+	}
+
+	lockfilePath := path.Join(dir, ".terraform.lock.hcl")
+	lockfile, err := os.ReadFile(lockfilePath)
+	if err != nil {
+		t.Fatalf("Lock file not created: %v", err)
+	}
+	providers, err := terraform.ParseTfLockfile(lockfile, lockfilePath)
+	if err != nil {
+		t.Fatalf("ParseTfLockfile failed: %v", err)
+	}
+	if len(providers) != 1 || providers[0].Version != "3.2.1" || len(providers[0].Hashes) != len(platforms) {
+		t.Errorf("Unexpected lock file content: %s", lockfile)
+	}
+	if _, err := os.Stat(path.Join(dir, ".terraform")); err == nil {
+		t.Errorf("FixLockfile left a .terraform dir behind")
+	}
+	if _, err := os.Stat(path.Join(dir, "other", ".terraform.lock.hcl")); err == nil {
+		t.Errorf("FixLockfile locked a dir whose lockfile check did not fail")
+	}
+}
@@ -0,0 +1,118 @@
+package terraform
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/rs/zerolog/log"
+	"github.com/terraform-tools/terraform-checker/pkg/utils"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FindLocalModules returns the dirs of the local modules called by the terraform files of dir,
+// or by its terragrunt.hcl. Only sources starting with ./ or ../ are local, the other ones are
+// downloaded by terraform.
+func FindLocalModules(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	modules := []string{}
+	if moduleDir := terragruntModuleDir(dir); moduleDir != "" {
+		modules = append(modules, moduleDir)
+	}
+	for _, f := range files {
+		file, diags := parser.ParseHCLFile(f)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		content, _, _ := file.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{{Type: "module", LabelNames: []string{"name"}}},
+		})
+		for _, moduleBlock := range content.Blocks {
+			moduleContent, _, _ := moduleBlock.Body.PartialContent(&hcl.BodySchema{
+				Attributes: []hcl.AttributeSchema{{Name: "source"}},
+			})
+			attr, ok := moduleContent.Attributes["source"]
+			if !ok {
+				continue
+			}
+			val, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || val.IsNull() || val.Type() != cty.String {
+				continue
+			}
+
+			source := val.AsString()
+			if !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") {
+				continue
+			}
+			if moduleDir := filepath.Join(dir, source); !utils.StrInSlice(modules, moduleDir) {
+				modules = append(modules, moduleDir)
+			}
+		}
+	}
+	return modules, nil
+}
+
+// ModuleGraph links the TfDirs to the ones calling them as local modules.
+type ModuleGraph struct {
+	// dependents maps the path of a module to the paths of the TfDirs calling it
+	dependents map[string][]string
+}
+
+// NewModuleGraph parses the module calls of every TfDir. TfDirs that cannot be parsed are
+// left out of the graph, terraform validate reports their errors.
+func NewModuleGraph(tfDirs []*TfDir) *ModuleGraph {
+	g := &ModuleGraph{dependents: make(map[string][]string)}
+	for _, tfDir := range tfDirs {
+		modules, err := FindLocalModules(tfDir.Path())
+		if err != nil {
+			log.Warn().Err(err).Msgf("Error parsing the modules of tfDir %s", tfDir.Path())
+			continue
+		}
+		for _, module := range modules {
+			g.dependents[module] = append(g.dependents[module], tfDir.Path())
+		}
+	}
+	return g
+}
+
+// Dependents returns the paths of the TfDirs calling the module at path, directly or not.
+func (g *ModuleGraph) Dependents(path string) []string {
+	dependents := []string{}
+	queue := []string{path}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range g.dependents[current] {
+			if dependent != path && !utils.StrInSlice(dependents, dependent) {
+				dependents = append(dependents, dependent)
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	return dependents
+}
+
+// Affected returns the TfDirs among tfDirs that are changed, or that call one of the
+// changed ones as a module, directly or not.
+func (g *ModuleGraph) Affected(tfDirs []*TfDir, changed []*TfDir) []*TfDir {
+	affectedPaths := []string{}
+	for _, tfDir := range changed {
+		affectedPaths = append(affectedPaths, tfDir.Path())
+		affectedPaths = append(affectedPaths, g.Dependents(tfDir.Path())...)
+	}
+
+	affected := []*TfDir{}
+	for _, tfDir := range tfDirs {
+		if utils.StrInSlice(affectedPaths, tfDir.Path()) {
+			affected = append(affected, tfDir)
+		}
+	}
+	return affected
+}
@@ -0,0 +1,77 @@
+package terraform_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+// newModulesRepo creates a repo where live/prod calls modules/network, which calls modules/shared.
+func newModulesRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"live/prod/main.tf":       "module \"network\" {\n  source = \"../../modules/network\"\n}\n\nmodule \"vpc\" {\n  source = \"terraform-aws-modules/vpc/aws\"\n}\n",
+		"live/dev/main.tf":        "locals {}\n",
+		"modules/network/main.tf": "module \"shared\" {\n  source = \"./../shared\"\n}\n",
+		"modules/shared/main.tf":  "locals {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Error creating fixture %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Error writing fixture %v", err)
+		}
+	}
+	return dir
+}
+
+func TestFindLocalModules(t *testing.T) {
+	t.Parallel()
+	dir := newModulesRepo(t)
+
+	modules, err := terraform.FindLocalModules(filepath.Join(dir, "live/prod"))
+	if err != nil {
+		t.Fatalf("FindLocalModules failed: %v", err)
+	}
+	if expected := []string{filepath.Join(dir, "modules/network")}; !reflect.DeepEqual(modules, expected) {
+		t.Errorf("Expected modules %v, got %v", expected, modules)
+	}
+}
+
+func TestModuleGraphAffected(t *testing.T) {
+	t.Parallel()
+	dir := newModulesRepo(t)
+	tfDirs := terraform.FindAllTfDir(dir)
+	graph := terraform.NewModuleGraph(tfDirs)
+
+	testCases := []struct {
+		changedFile string
+		expected    []string
+	}{
+		{changedFile: "modules/shared/main.tf", expected: []string{"live/prod", "modules/network", "modules/shared"}},
+		{changedFile: "modules/network/main.tf", expected: []string{"live/prod", "modules/network"}},
+		{changedFile: "live/dev/main.tf", expected: []string{"live/dev"}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.changedFile, func(t *testing.T) {
+			t.Parallel()
+			affected := graph.Affected(tfDirs, terraform.ChangedTfDirs(tfDirs, dir, []string{tc.changedFile}))
+
+			paths := []string{}
+			for _, tfDir := range affected {
+				rel, _ := filepath.Rel(dir, tfDir.Path())
+				paths = append(paths, rel)
+			}
+			if !reflect.DeepEqual(paths, tc.expected) {
+				t.Errorf("Expected affected tfDirs %v, got %v", tc.expected, paths)
+			}
+		})
+	}
+}
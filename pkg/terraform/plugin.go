@@ -0,0 +1,157 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terraform-tools/terraform-checker/pkg/errors"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin"
+)
+
+const (
+	maxActionLabelLength       = 20
+	maxActionDescriptionLength = 40
+)
+
+// ValidatePlugin checks a plugin config, its name must not collide with a built-in check.
+func ValidatePlugin(p plugin.Config) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if _, ok := DefaultRegistry().Get(p.Name); ok {
+		return errors.PluginNotValidError(fmt.Sprintf("name %s is already used by a built-in check", p.Name))
+	}
+	return nil
+}
+
+// validateDirPlugin checks a plugin declared in a .tf-checker file. gRPC plugins are refused, as
+// they would be started from the working dir of the server and kept running for every repository.
+func validateDirPlugin(p plugin.Config) error {
+	if err := ValidatePlugin(p); err != nil {
+		return err
+	}
+	if p.Protocol == plugin.ProtocolGRPC {
+		return errors.PluginNotValidError(fmt.Sprintf("protocol %s is only allowed in the server config", plugin.ProtocolGRPC))
+	}
+	return nil
+}
+
+// pluginCheckDefinition returns the check of a plugin, defaultEnabled is false for the plugins
+// of .tf-checker files as they run commands of the checked repository.
+func pluginCheckDefinition(p plugin.Config, pluginManager *plugin.Manager, defaultEnabled bool) CheckDefinition {
+	description := p.Description
+	if description == "" {
+		description = fmt.Sprintf("plugin %s", p.Name)
+	}
+
+	factory := func(tfDir, relDir string) TfCheck {
+		check, err := pluginManager.Check(p)
+		return NewTfCheckPlugin(p.Name, check, err, tfDir, relDir)
+	}
+
+	return CheckDefinition{
+		Name:           p.Name,
+		Description:    description,
+		DefaultEnabled: defaultEnabled,
+		DependsOn:      p.DependsOn,
+		Factory:        factory,
+		// Plugins get the dir and decide on their own how to check a Terragrunt unit
+		TerragruntFactory: factory,
+		Fix: func(ctx context.Context, _ ExecConfig, tfDir *TfDir, relDir string) error {
+			return fixTfDirPlugin(ctx, p, pluginManager, tfDir, relDir)
+		},
+	}
+}
+
+// RegisterPlugins registers a check for each of the plugins.
+func RegisterPlugins(r *Registry, plugins []plugin.Config, pluginManager *plugin.Manager) error {
+	for _, p := range plugins {
+		if err := r.Register(pluginCheckDefinition(p, pluginManager, true)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DirRegistry returns the registry to use for a TfDir: r, extended with the plugins
+// declared in its .tf-checker file if allowed.
+func DirRegistry(r *Registry, tfDir *TfDir, allowDirPlugins bool, pluginManager *plugin.Manager) *Registry {
+	if !allowDirPlugins || len(tfDir.Plugins()) == 0 {
+		return r
+	}
+
+	dirRegistry := r.Clone()
+	for _, p := range tfDir.Plugins() {
+		if err := dirRegistry.Register(pluginCheckDefinition(p, pluginManager, false)); err != nil {
+			log.Error().Err(err).Msgf("skipping plugin %s of tfDir %s", p.Name, tfDir.Path())
+		}
+	}
+	return dirRegistry
+}
+
+// DirPluginNames returns the names of the plugins declared in the .tf-checker files of the TfDirs.
+func DirPluginNames(tfDirs []*TfDir) []string {
+	plugins := []plugin.Config{}
+	for _, tfDir := range tfDirs {
+		plugins = append(plugins, tfDir.Plugins()...)
+	}
+	return plugin.Names(plugins)
+}
+
+// DirPlugins returns the plugins that can run on a TfDir: the globally configured ones,
+// and the ones declared in its .tf-checker file if allowed.
+func DirPlugins(tfDir *TfDir, plugins []plugin.Config, allowDirPlugins bool) []plugin.Config {
+	if !allowDirPlugins {
+		return plugins
+	}
+	return append(append([]plugin.Config{}, plugins...), tfDir.Plugins()...)
+}
+
+// FixPlugin asks the plugin named name to fix every TfDir.
+func FixPlugin(ctx context.Context, cloneDir, name string, plugins []plugin.Config, allowDirPlugins bool, pluginManager *plugin.Manager) error {
+	for _, tfDir := range FindAllTfDir(cloneDir) {
+		if !tfDir.IsEnabled() {
+			continue
+		}
+
+		p := plugin.Find(DirPlugins(tfDir, plugins, allowDirPlugins), name)
+		if p == nil {
+			continue
+		}
+
+		relDir := strings.TrimPrefix(strings.ReplaceAll(tfDir.Path(), cloneDir, ""), "/")
+		if err := fixTfDirPlugin(ctx, *p, pluginManager, tfDir, relDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fixTfDirPlugin(ctx context.Context, p plugin.Config, pluginManager *plugin.Manager, tfDir *TfDir, relDir string) error {
+	check, err := pluginManager.Check(p)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Executing action %s on tfDir: %s", p.Name, tfDir.Path())
+	req := plugin.Request{Dir: tfDir.Path(), RelDir: relDir}
+	var out string
+	if c, ok := check.(plugin.ContextCheck); ok {
+		out, err = c.FixContext(ctx, req)
+	} else {
+		out, err = check.Fix(req)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, out)
+	}
+	return nil
+}
+
+func truncate(s string, length int) string {
+	if r := []rune(s); len(r) > length {
+		return string(r[:length])
+	}
+	return s
+}
@@ -0,0 +1,132 @@
+package terraform_test
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+// The plugin fails until a fixed file exists in the checked dir.
+const testPluginScript = `#!/bin/sh
+read -r request
+if [ -f fixed ]; then
+  echo '{"ok": true}'
+  exit 0
+fi
+echo '{"ok": false, "output": "not fixed", "fix_command": ["touch", "fixed"],
+  "annotations": [{"path": "main.tf", "start_line": 1, "severity": "error", "title": "t", "message": "m"}]}'
+`
+
+func newTestPluginDir(t *testing.T) (string, plugin.Config) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(path.Join(dir, "main.tf"), []byte("locals {}\n"), 0o600); err != nil {
+		t.Fatalf("Error writing fixture %v", err)
+	}
+	script := path.Join(dir, "check.sh")
+	if err := os.WriteFile(script, []byte(testPluginScript), 0o700); err != nil { //nolint:gosec
+		t.Fatalf("Error writing fixture %v", err)
+	}
+	return dir, plugin.Config{Name: "custom", Command: script}
+}
+
+func newTestPluginRegistry(t *testing.T, p plugin.Config) *terraform.Registry {
+	t.Helper()
+	registry := terraform.DefaultRegistry()
+	if err := terraform.RegisterPlugins(registry, []plugin.Config{p}, plugin.NewManager()); err != nil {
+		t.Fatalf("RegisterPlugins failed: %v", err)
+	}
+	return registry
+}
+
+func TestTfCheckPlugin(t *testing.T) {
+	t.Parallel()
+	dir, p := newTestPluginDir(t)
+
+	checks, err := newTestPluginRegistry(t, p).GetTfChecks(dir, "rel", []string{"custom", "unknown"})
+	if err != nil {
+		t.Fatalf("GetTfChecks failed: %v", err)
+	}
+	if len(checks) != 1 {
+		t.Fatalf("Expected 1 check, got %v", len(checks))
+	}
+
+	check := checks[0]
+	check.Run(context.Background())
+	if check.IsOK() || check.Output() != "not fixed" || check.Name() != "custom" {
+		t.Errorf("Unexpected plugin check result: ok %v, output %v", check.IsOK(), check.Output())
+	}
+	if annotations := check.Annotations(); len(annotations) != 1 || annotations[0].GetPath() != "rel/main.tf" || annotations[0].GetAnnotationLevel() != "failure" {
+		t.Errorf("Unexpected plugin annotations %v", annotations)
+	}
+	if action := check.FixAction(); action == nil || action.Identifier != "custom" {
+		t.Errorf("Expected a fix action, got %v", action)
+	}
+}
+
+func TestFixPlugin(t *testing.T) {
+	t.Parallel()
+	dir, p := newTestPluginDir(t)
+
+	if err := terraform.FixPlugin(context.Background(), dir, "custom", []plugin.Config{p}, false, plugin.NewManager()); err != nil {
+		t.Fatalf("FixPlugin failed: %v", err)
+	}
+
+	checks, err := newTestPluginRegistry(t, p).GetTfChecks(dir, "", []string{"custom"})
+	if err != nil || len(checks) != 1 {
+		t.Fatalf("GetTfChecks failed: %v", err)
+	}
+	check := checks[0]
+	check.Run(context.Background())
+	if !check.IsOK() {
+		t.Errorf("Expected plugin check to succeed after fix, output %v", check.Output())
+	}
+}
+
+func TestRunCheckTimeout(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	script := path.Join(dir, "slow.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nsleep 30\n"), 0o700); err != nil { //nolint:gosec
+		t.Fatalf("Error writing fixture %v", err)
+	}
+	p := plugin.Config{Name: "slow", Command: script}
+
+	checks, err := newTestPluginRegistry(t, p).GetTfChecks(dir, "", []string{"slow"})
+	if err != nil || len(checks) != 1 {
+		t.Fatalf("GetTfChecks failed: %v", err)
+	}
+	check := checks[0]
+	start := time.Now()
+	terraform.RunCheck(context.Background(), check, 100*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the check to be killed on timeout, took %v", elapsed)
+	}
+	if check.IsOK() || !strings.HasPrefix(check.Output(), "Timed out after 100ms") {
+		t.Errorf("Expected the check to time out, output %v", check.Output())
+	}
+	if conclusion := check.FailureConclusion(); conclusion != githubv4.CheckConclusionStateTimedOut {
+		t.Errorf("Expected conclusion %v, got %v", githubv4.CheckConclusionStateTimedOut, conclusion)
+	}
+}
+
+func TestDirPlugins(t *testing.T) {
+	t.Parallel()
+	dir, p := newTestPluginDir(t)
+	config := "plugins:\n  - name: custom\n    command: " + p.Command + "\n  - name: long-lived\n    command: " + p.Command + "\n    protocol: grpc\n"
+	if err := os.WriteFile(path.Join(dir, ".tf-checker"), []byte(config), 0o600); err != nil {
+		t.Fatalf("Error writing fixture %v", err)
+	}
+
+	plugins := terraform.NewTfDir(dir).Plugins()
+	if len(plugins) != 1 || plugins[0].Name != "custom" {
+		t.Errorf("Expected the gRPC plugin of the .tf-checker file to be skipped, got %+v", plugins)
+	}
+}
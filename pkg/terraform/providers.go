@@ -0,0 +1,115 @@
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/rs/zerolog/log"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	tfPluginCacheDirEnvVarName = "TF_PLUGIN_CACHE_DIR"
+	tfCLIConfigFileEnvVarName  = "TF_CLI_CONFIG_FILE"
+	tfCLIConfigFilePattern     = "tf-checker-*.tfrc"
+	tfPluginCacheDirMode       = 0o755
+	tfCLIConfigFileMode        = 0o600
+)
+
+// pluginCacheLocks holds a lock per plugin cache dir, terraform does not support concurrent writes
+// to a cache. As a result, the terraform init sharing a cache run one at a time whatever the number
+// of queue workers, the plugin cache trades this throughput for the provider downloads it saves.
+var pluginCacheLocks sync.Map //nolint:gochecknoglobals // a cache dir may be shared by several ExecConfig
+
+// ProviderInstallation configures where terraform gets the providers from.
+type ProviderInstallation struct {
+	// PluginCacheDir is shared by every terraform init
+	PluginCacheDir string `yaml:"plugin_cache_dir" json:"plugin_cache_dir"` //nolint:tagliatelle
+	// FilesystemMirror and NetworkMirror replace the registries, so that checks can run offline
+	FilesystemMirror string `yaml:"filesystem_mirror" json:"filesystem_mirror"` //nolint:tagliatelle
+	NetworkMirror    string `yaml:"network_mirror" json:"network_mirror"`       //nolint:tagliatelle
+}
+
+// HasMirror returns true if providers are installed from a mirror.
+func (p ProviderInstallation) HasMirror() bool {
+	return p.FilesystemMirror != "" || p.NetworkMirror != ""
+}
+
+// CLIConfig returns the terraform CLI configuration installing providers from the mirrors only.
+func (p ProviderInstallation) CLIConfig() []byte {
+	f := hclwrite.NewEmptyFile()
+	body := f.Body().AppendNewBlock("provider_installation", nil).Body()
+	if p.FilesystemMirror != "" {
+		body.AppendNewBlock("filesystem_mirror", nil).Body().SetAttributeValue("path", cty.StringVal(p.FilesystemMirror))
+	}
+	if p.NetworkMirror != "" {
+		body.AppendNewBlock("network_mirror", nil).Body().SetAttributeValue("url", cty.StringVal(p.NetworkMirror))
+	}
+	return f.Bytes()
+}
+
+// setup creates the plugin cache dir, and writes the CLI config installing providers from the
+// mirrors if any, returning its path.
+func (p ProviderInstallation) setup() (string, error) {
+	if p.PluginCacheDir != "" {
+		// terraform ignores a cache dir that does not exist
+		if err := os.MkdirAll(p.PluginCacheDir, tfPluginCacheDirMode); err != nil {
+			return "", err
+		}
+		log.Info().Msgf("Using terraform plugin cache %s", p.PluginCacheDir)
+	}
+
+	if !p.HasMirror() {
+		return "", nil
+	}
+	if current := os.Getenv(tfCLIConfigFileEnvVarName); current != "" {
+		log.Warn().Msgf("%s %s is replaced to install providers from mirrors", tfCLIConfigFileEnvVarName, current)
+	}
+	f, err := os.CreateTemp("", tfCLIConfigFilePattern)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if err := f.Chmod(tfCLIConfigFileMode); err != nil {
+		return "", err
+	}
+	if _, err := f.Write(p.CLIConfig()); err != nil {
+		return "", err
+	}
+	log.Info().Msgf("Installing terraform providers from mirrors with CLI config %s", f.Name())
+	return f.Name(), nil
+}
+
+// providersLockOptions returns the options making terraform providers lock use the mirror,
+// it ignores the provider installation of the CLI config. Both mirrors cannot be used at once,
+// the filesystem one is preferred.
+func (p ProviderInstallation) providersLockOptions() []tfexec.ProvidersLockOption {
+	switch {
+	case p.FilesystemMirror != "":
+		return []tfexec.ProvidersLockOption{tfexec.FSMirror(p.FilesystemMirror)}
+	case p.NetworkMirror != "":
+		return []tfexec.ProvidersLockOption{tfexec.NetMirror(p.NetworkMirror)}
+	default:
+		return nil
+	}
+}
+
+// lockPluginCache locks the plugin cache if one is used, the returned func unlocks it. It gives
+// up with the error of ctx if ctx is done before the lock is acquired.
+func (p ProviderInstallation) lockPluginCache(ctx context.Context) (func(), error) {
+	if p.PluginCacheDir == "" {
+		return func() {}, nil
+	}
+	value, _ := pluginCacheLocks.LoadOrStore(filepath.Clean(p.PluginCacheDir), make(chan struct{}, 1))
+	lock, _ := value.(chan struct{})
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,73 @@
+package terraform_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+func TestProviderInstallationCLIConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		p        terraform.ProviderInstallation
+		mirror   bool
+		expected []string
+	}{
+		{
+			name:   "no mirror",
+			p:      terraform.ProviderInstallation{PluginCacheDir: "/cache"},
+			mirror: false,
+		}, {
+			name:     "filesystem mirror",
+			p:        terraform.ProviderInstallation{FilesystemMirror: "/mirror"},
+			mirror:   true,
+			expected: []string{"filesystem_mirror {", `path = "/mirror"`},
+		}, {
+			name:     "both mirrors",
+			p:        terraform.ProviderInstallation{FilesystemMirror: "/mirror", NetworkMirror: "https://mirror.example.com/"},
+			mirror:   true,
+			expected: []string{`path = "/mirror"`, "network_mirror {", `url = "https://mirror.example.com/"`},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if tc.p.HasMirror() != tc.mirror {
+				t.Errorf("Expected HasMirror %v", tc.mirror)
+			}
+
+			config := string(tc.p.CLIConfig())
+			if !strings.HasPrefix(config, "provider_installation {") || strings.Contains(config, "direct") {
+				t.Errorf("Unexpected CLI config %s", config)
+			}
+			for _, s := range tc.expected {
+				if !strings.Contains(config, s) {
+					t.Errorf("CLI config does not contain %s:\n%s", s, config)
+				}
+			}
+		})
+	}
+}
+
+func TestExecConfigSetup(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	config := terraform.ExecConfig{ProviderInstallation: terraform.ProviderInstallation{PluginCacheDir: cacheDir, FilesystemMirror: "/mirror"}}
+	if err := config.Setup(); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if info, err := os.Stat(cacheDir); err != nil || !info.IsDir() {
+		t.Errorf("Setup did not create the plugin cache dir %s", cacheDir)
+	}
+	// The settings are given to the terraform commands only
+	if os.Getenv("TF_CLI_CONFIG_FILE") != "" || os.Getenv("TF_PLUGIN_CACHE_DIR") == cacheDir {
+		t.Errorf("Setup changed the environment of the process")
+	}
+}
@@ -0,0 +1,234 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/terraform-tools/terraform-checker/pkg/errors"
+	"github.com/terraform-tools/terraform-checker/pkg/utils"
+)
+
+// Names of the built-in checks.
+const (
+	Fmt      = "fmt"
+	Validate = "validate"
+	TFLint   = "tflint"
+	Lockfile = "lockfile"
+)
+
+// CheckFactory creates the check of a dir.
+type CheckFactory func(tfDir, relDir string) TfCheck
+
+// CheckFixer fixes in place the issues reported by a check on a TfDir, running terraform with config.
+type CheckFixer func(ctx context.Context, config ExecConfig, tfDir *TfDir, relDir string) error
+
+// CheckDefinition describes a type of check that can be registered.
+type CheckDefinition struct {
+	Name        string
+	Description string
+	Factory     CheckFactory
+	// TerragruntFactory creates the check of a Terragrunt unit, checks without it are not run on them
+	TerragruntFactory CheckFactory
+	// Fix is optional
+	Fix CheckFixer
+	// DefaultEnabled checks are run when no check is explicitly selected
+	DefaultEnabled bool
+	// DependsOn lists the checks that are run beforehand on the same dir whenever this one is
+	DependsOn []string
+}
+
+// Registry holds the checks that can be run, keyed by name.
+type Registry struct {
+	mu          sync.RWMutex
+	definitions map[string]CheckDefinition
+	// names keeps the registration order
+	names []string
+	// execConfig is given to the checks and the fixes
+	execConfig ExecConfig
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		definitions: make(map[string]CheckDefinition),
+	}
+}
+
+// DefaultRegistry returns a registry holding all of the built-in checks.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	for _, def := range []CheckDefinition{
+		fmtCheckDefinition(),
+		validateCheckDefinition(),
+		tfLintCheckDefinition(),
+		lockfileCheckDefinition(),
+	} {
+		if err := r.Register(def); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds a check to the registry, its name must be unique.
+func (r *Registry) Register(def CheckDefinition) error {
+	if def.Name == "" || def.Factory == nil {
+		return errors.CheckNotValidError("name and factory are mandatory")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.definitions[def.Name]; ok {
+		return errors.CheckNotValidError(fmt.Sprintf("check %s is already registered", def.Name))
+	}
+	r.definitions[def.Name] = def
+	r.names = append(r.names, def.Name)
+	return nil
+}
+
+// Clone returns a copy of the registry, in which more checks can be registered.
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clone := NewRegistry()
+	for _, name := range r.names {
+		clone.definitions[name] = r.definitions[name]
+	}
+	clone.names = append(clone.names, r.names...)
+	clone.execConfig = r.execConfig
+	return clone
+}
+
+// SetExecConfig sets how the checks and the fixes of the registry run terraform.
+func (r *Registry) SetExecConfig(config ExecConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.execConfig = config
+}
+
+// ExecConfig returns how the checks and the fixes of the registry run terraform.
+func (r *Registry) ExecConfig() ExecConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.execConfig
+}
+
+// Get returns the definition of the check named name.
+func (r *Registry) Get(name string) (CheckDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.definitions[name]
+	return def, ok
+}
+
+// Definitions returns all of the registered checks, in registration order.
+func (r *Registry) Definitions() []CheckDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	defs := make([]CheckDefinition, 0, len(r.names))
+	for _, name := range r.names {
+		defs = append(defs, r.definitions[name])
+	}
+	return defs
+}
+
+// Names returns the names of all of the registered checks.
+func (r *Registry) Names() []string {
+	names := []string{}
+	for _, def := range r.Definitions() {
+		names = append(names, def.Name)
+	}
+	return names
+}
+
+// DefaultNames returns the names of the checks enabled by default.
+func (r *Registry) DefaultNames() []string {
+	names := []string{}
+	for _, def := range r.Definitions() {
+		if def.DefaultEnabled {
+			names = append(names, def.Name)
+		}
+	}
+	return names
+}
+
+// Unknown returns the names that are not registered.
+func (r *Registry) Unknown(names []string) []string {
+	unknown := []string{}
+	for _, name := range names {
+		if _, ok := r.Get(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// Resolve adds the dependencies of the named checks, and orders them so that
+// dependencies come first. Unknown names are skipped.
+func (r *Registry) Resolve(names []string) ([]string, error) {
+	resolved := []string{}
+	visiting := []string{}
+
+	var visit func(name string, dependent string) error
+	visit = func(name string, dependent string) error {
+		if utils.StrInSlice(resolved, name) {
+			return nil
+		}
+		if utils.StrInSlice(visiting, name) {
+			return errors.CheckNotValidError(fmt.Sprintf("dependency cycle on check %s", name))
+		}
+		def, ok := r.Get(name)
+		if !ok {
+			if dependent != "" {
+				return errors.CheckNotValidError(fmt.Sprintf("check %s depends on unknown check %s", dependent, name))
+			}
+			return nil
+		}
+
+		visiting = append(visiting, name)
+		for _, dep := range def.DependsOn {
+			if err := visit(dep, name); err != nil {
+				return err
+			}
+		}
+		resolved = append(resolved, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, ""); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// GetTfChecks returns the checks of a dir for the named checks and their dependencies.
+// The built-in checks share a TfExec, so that terraform init is run once for the dir.
+// Checks that do not support Terragrunt units are left out for them.
+func (r *Registry) GetTfChecks(tfDir, relDir string, names []string) ([]TfCheck, error) {
+	resolved, err := r.Resolve(names)
+	if err != nil {
+		return nil, err
+	}
+
+	tfExec := NewTfExec(tfDir, r.ExecConfig())
+	terragrunt := IsTerragruntUnit(tfDir)
+	checks := make([]TfCheck, 0, len(resolved))
+	for _, name := range resolved {
+		def, _ := r.Get(name)
+		factory := def.Factory
+		if terragrunt {
+			if factory = def.TerragruntFactory; factory == nil {
+				continue
+			}
+		}
+		check := factory(tfDir, relDir)
+		if c, ok := check.(interface{ setTfExec(e *TfExec) }); ok {
+			c.setTfExec(tfExec)
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
@@ -0,0 +1,86 @@
+package terraform_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+func newTestRegistry(t *testing.T, defs ...terraform.CheckDefinition) *terraform.Registry {
+	t.Helper()
+	registry := terraform.NewRegistry()
+	for _, def := range defs {
+		if def.Factory == nil {
+			def.Factory = func(tfDir, relDir string) terraform.TfCheck { return nil }
+		}
+		if err := registry.Register(def); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+	}
+	return registry
+}
+
+func TestRegistryRegister(t *testing.T) {
+	t.Parallel()
+	registry := terraform.DefaultRegistry()
+
+	if err := registry.Register(terraform.CheckDefinition{Name: terraform.Fmt, Factory: func(tfDir, relDir string) terraform.TfCheck { return nil }}); err == nil {
+		t.Errorf("Expected an error registering %s twice", terraform.Fmt)
+	}
+	if err := registry.Register(terraform.CheckDefinition{Name: "nofactory"}); err == nil {
+		t.Errorf("Expected an error registering a check without factory")
+	}
+
+	expected := []string{terraform.Fmt, terraform.Validate, terraform.TFLint, terraform.Lockfile}
+	if names := registry.DefaultNames(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected default checks %v, got %v", expected, names)
+	}
+}
+
+func TestRegistryResolve(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		defs     []terraform.CheckDefinition
+		names    []string
+		expected []string
+		err      bool
+	}{
+		{
+			name:     "dependencies first",
+			defs:     []terraform.CheckDefinition{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"c"}}, {Name: "c"}},
+			names:    []string{"a", "c"},
+			expected: []string{"c", "b", "a"},
+		}, {
+			name:     "unknown skipped",
+			defs:     []terraform.CheckDefinition{{Name: "a"}},
+			names:    []string{"unknown", "a"},
+			expected: []string{"a"},
+		}, {
+			name:  "unknown dependency",
+			defs:  []terraform.CheckDefinition{{Name: "a", DependsOn: []string{"unknown"}}},
+			names: []string{"a"},
+			err:   true,
+		}, {
+			name:  "cycle",
+			defs:  []terraform.CheckDefinition{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}},
+			names: []string{"a"},
+			err:   true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			resolved, err := newTestRegistry(t, tc.defs...).Resolve(tc.names)
+			if (err != nil) != tc.err {
+				t.Fatalf("Resolve returned error %v, expected error %v", err, tc.err)
+			}
+			if !tc.err && !reflect.DeepEqual(resolved, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, resolved)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/rs/zerolog/log"
+	"github.com/terraform-tools/terraform-checker/pkg/utils"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	TerragruntPath              = "terragrunt"
+	terragruntConfigName        = "terragrunt.hcl"
+	terragruntCacheDirName      = ".terragrunt-cache"
+	terragruntTfPathEnvVarName  = "TERRAGRUNT_TFPATH"
+	terragruntNonInteractiveEnv = "TERRAGRUNT_NON_INTERACTIVE=true"
+)
+
+// terragruntConfig holds the parts of a terragrunt.hcl file the checker needs.
+type terragruntConfig struct {
+	// unit is true if the file configures a unit, a file only included by the units doesn't
+	unit   bool
+	source string
+	// sourceRange, inputsRange and inputRanges locate the issues reported on the unit
+	sourceRange hcl.Range
+	inputsRange hcl.Range
+	inputRanges map[string]hcl.Range
+}
+
+func parseTerragruntConfig(dir string) (*terragruntConfig, error) {
+	file, diags := hclparse.NewParser().ParseHCLFile(filepath.Join(dir, terragruntConfigName))
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	c := &terragruntConfig{
+		inputRanges: make(map[string]hcl.Range),
+		sourceRange: hcl.Range{Filename: terragruntConfigName, Start: hcl.InitialPos, End: hcl.InitialPos},
+	}
+	c.inputsRange = c.sourceRange
+
+	// The syntax body is walked directly, as include blocks are labeled or not depending
+	// on the terragrunt version
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return c, nil
+	}
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "include":
+			c.unit = true
+		case "terraform":
+			c.unit = true
+			c.sourceRange = block.TypeRange
+			if attr, ok := block.Body.Attributes["source"]; ok {
+				c.sourceRange = attr.SrcRange
+				if val, diags := attr.Expr.Value(nil); !diags.HasErrors() && !val.IsNull() && val.Type() == cty.String {
+					c.source = val.AsString()
+				}
+			}
+		}
+	}
+
+	if attr, ok := body.Attributes["inputs"]; ok {
+		c.inputsRange = attr.NameRange
+		if expr, ok := attr.Expr.(*hclsyntax.ObjectConsExpr); ok {
+			for _, item := range expr.Items {
+				if key := objectKey(item.KeyExpr); key != "" {
+					c.inputRanges[key] = item.KeyExpr.Range()
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+// IsTerragruntUnit returns true if dir holds a terragrunt.hcl configuring a unit, that is having
+// a terraform or include block. Files without them are the parent configs included by the units.
+func IsTerragruntUnit(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, terragruntConfigName)); err != nil {
+		return false
+	}
+	c, err := parseTerragruntConfig(dir)
+	if err != nil {
+		// Reported by the checks of the unit
+		log.Warn().Err(err).Msgf("Error parsing %s of %s", terragruntConfigName, dir)
+		return true
+	}
+	return c.unit
+}
+
+// terragruntModuleDir returns the dir of the module of a unit if it is a local one, empty otherwise.
+func terragruntModuleDir(dir string) string {
+	if _, err := os.Stat(filepath.Join(dir, terragruntConfigName)); err != nil {
+		return ""
+	}
+	c, err := parseTerragruntConfig(dir)
+	if err != nil || (!strings.HasPrefix(c.source, "./") && !strings.HasPrefix(c.source, "../")) {
+		return ""
+	}
+	// The module of a repo is given after a double slash, e.g. ../modules//vpc
+	return filepath.Join(dir, c.source)
+}
+
+// terragruntCommand returns the terragrunt command to run in the dir of e, using the engine of e.
+func terragruntCommand(ctx context.Context, e *TfExec, args ...string) (*exec.Cmd, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	cmd := utils.CommandContext(ctx, TerragruntPath, args...) // #nosec
+	cmd.Dir = e.dir
+	cmd.Env = append(e.config.environ(), terragruntTfPathEnvVarName+"="+e.execPath, terragruntNonInteractiveEnv)
+	return cmd, nil
+}
+
+// CheckTerragruntFmt checks the formatting of the terragrunt.hcl of a unit.
+func CheckTerragruntFmt(ctx context.Context, e *TfExec) (bool, string) {
+	cmd, err := terragruntCommand(ctx, e, "hclfmt", "--terragrunt-check", "--terragrunt-hclfmt-file", filepath.Join(e.Dir(), terragruntConfigName))
+	if err != nil {
+		return false, err.Error()
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, fmt.Sprintf("Your %s formatting is wrong:\n%s", terragruntConfigName, out) +
+			"\nplease run `terragrunt hclfmt` in the right dir or launch the `Trigger tf fmt` action ⬆️⬆️⬆️" + "\n\n" +
+			"more info [here](https://terragrunt.gruntwork.io/docs/reference/cli-options/#hclfmt)"
+	}
+	return true, ""
+}
+
+func fixTerragruntFmt(ctx context.Context, e *TfExec) error {
+	cmd, err := terragruntCommand(ctx, e, "hclfmt", "--terragrunt-hclfmt-file", filepath.Join(e.Dir(), terragruntConfigName))
+	if err != nil {
+		return err
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("terragrunt hclfmt failed: %s", out)
+	}
+	return nil
+}
+
+// TerragruntInputs are the inputs of a unit that do not match the variables of its module.
+type TerragruntInputs struct {
+	Unused  []string
+	Missing []string
+}
+
+// ParseTerragruntValidateInputs returns the inputs reported by terragrunt validate-inputs.
+func ParseTerragruntValidateInputs(output string) TerragruntInputs {
+	var inputs TerragruntInputs
+	var current *[]string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.Contains(line, "inputs passed in by terragrunt are unused"):
+			current = &inputs.Unused
+		case strings.Contains(line, "required inputs are missing"):
+			current = &inputs.Missing
+		case strings.HasPrefix(line, "- ") && current != nil:
+			*current = append(*current, strings.TrimSpace(strings.TrimPrefix(line, "- ")))
+		case line != "" && !strings.HasPrefix(line, "- "):
+			current = nil
+		}
+	}
+	return inputs
+}
+
+// CheckTerragruntValidate runs terragrunt validate-inputs and validate in a unit, terraform is
+// run by terragrunt in the cache dir where it generates the configuration of the unit.
+// validate is run once with -json, its output is rendered from the diagnostics.
+func CheckTerragruntValidate(ctx context.Context, e *TfExec) (bool, string, TerragruntInputs, *tfjson.ValidateOutput) {
+	cmd, err := terragruntCommand(ctx, e, "validate-inputs")
+	if err != nil {
+		return false, err.Error(), TerragruntInputs{}, nil
+	}
+	inputsOut, inputsErr := cmd.CombinedOutput()
+	inputs := ParseTerragruntValidateInputs(string(inputsOut))
+
+	// The diagnostics are written on stdout, terragrunt logs on stderr
+	cmd, _ = terragruntCommand(ctx, e, "validate", "-no-color", "-json")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	jsonOut, validateErr := cmd.Output()
+	output := string(inputsOut) + "\n"
+	var exitErr *exec.ExitError
+	if validateErr != nil && (!errors.As(validateErr, &exitErr) || exitErr.ExitCode() != 1) {
+		log.Error().Err(validateErr).Msg("error running terragrunt validate")
+		return false, output + stderr.String(), inputs, nil
+	}
+	outJSON, err := parseValidateOutput(jsonOut)
+	if err != nil {
+		log.Error().Err(err).Msg("error unmarshalling terragrunt validate output")
+		return false, output + stderr.String(), inputs, nil
+	}
+	output += FormatValidateOutput(outJSON)
+
+	return inputsErr == nil && validateErr == nil && len(inputs.Missing) == 0, output, inputs, outJSON
+}
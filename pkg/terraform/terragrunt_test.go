@@ -0,0 +1,132 @@
+package terraform_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+// newTerragruntRepo creates a repo where the live/app unit uses the local modules/app module,
+// and live/db a remote one. live/terragrunt.hcl is the parent config included by the units.
+func newTerragruntRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"live/terragrunt.hcl":                       "remote_state {\n  backend = \"local\"\n  config  = {}\n}\n",
+		"live/app/terragrunt.hcl":                   "include \"root\" {\n  path = find_in_parent_folders()\n}\n\nterraform {\n  source = \"../../modules//app\"\n}\n\ninputs = {\n  name    = \"app\"\n  unknown = true\n}\n",
+		"live/db/terragrunt.hcl":                    "include {\n  path = find_in_parent_folders()\n}\n\nterraform {\n  source = \"git::https://example.com/modules.git//db?ref=v1.0.0\"\n}\n",
+		"live/db/.terragrunt-cache/abc/def/main.tf": "variable \"name\" {}\n",
+		"modules/app/main.tf":                       "variable \"name\" {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Error creating fixture %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Error writing fixture %v", err)
+		}
+	}
+	return dir
+}
+
+func TestFindAllTfDirTerragrunt(t *testing.T) {
+	t.Parallel()
+	dir := newTerragruntRepo(t)
+
+	found := map[string]bool{}
+	for _, tfDir := range terraform.FindAllTfDir(dir) {
+		rel, _ := filepath.Rel(dir, tfDir.Path())
+		found[rel] = tfDir.IsTerragrunt()
+	}
+	expected := map[string]bool{"live/app": true, "live/db": true, "modules/app": false}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Expected tfDirs %v, got %v", expected, found)
+	}
+}
+
+func TestTerragruntModuleGraph(t *testing.T) {
+	t.Parallel()
+	dir := newTerragruntRepo(t)
+	tfDirs := terraform.FindAllTfDir(dir)
+
+	dependents := terraform.NewModuleGraph(tfDirs).Dependents(filepath.Join(dir, "modules/app"))
+	if expected := []string{filepath.Join(dir, "live/app")}; !reflect.DeepEqual(dependents, expected) {
+		t.Errorf("Expected dependents %v, got %v", expected, dependents)
+	}
+}
+
+func TestGetTfChecksTerragrunt(t *testing.T) {
+	t.Parallel()
+	dir := newTerragruntRepo(t)
+
+	checks, err := terraform.DefaultRegistry().GetTfChecks(filepath.Join(dir, "live/app"), "live/app", terraform.DefaultRegistry().Names())
+	if err != nil {
+		t.Fatalf("GetTfChecks failed: %v", err)
+	}
+	names := []string{}
+	for _, check := range checks {
+		names = append(names, check.Name())
+		switch check.(type) {
+		case *terraform.TfCheckTerragruntFmt, *terraform.TfCheckTerragruntValidate:
+		default:
+			t.Errorf("Unexpected check %T for a terragrunt unit", check)
+		}
+	}
+	sort.Strings(names)
+	if expected := []string{terraform.Fmt, terraform.Validate}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected checks %v, got %v", expected, names)
+	}
+}
+
+func TestParseTerragruntValidateInputs(t *testing.T) {
+	t.Parallel()
+
+	output := `The following inputs passed in by terragrunt are unused:
+
+    - unknown
+    - other
+
+The following required inputs are missing:
+
+    - name
+
+`
+	inputs := terraform.ParseTerragruntValidateInputs(output)
+	expected := terraform.TerragruntInputs{Unused: []string{"unknown", "other"}, Missing: []string{"name"}}
+	if !reflect.DeepEqual(inputs, expected) {
+		t.Errorf("Expected inputs %+v, got %+v", expected, inputs)
+	}
+
+	if inputs := terraform.ParseTerragruntValidateInputs("All variables passed in by terragrunt are in use.\n"); len(inputs.Unused) != 0 || len(inputs.Missing) != 0 {
+		t.Errorf("Expected no inputs, got %+v", inputs)
+	}
+}
+
+func TestFormatValidateOutput(t *testing.T) {
+	t.Parallel()
+
+	invalid := &tfjson.ValidateOutput{
+		Valid:      false,
+		ErrorCount: 1,
+		Diagnostics: []tfjson.Diagnostic{{
+			Severity: tfjson.DiagnosticSeverityError,
+			Summary:  "Unsupported argument",
+			Detail:   `An argument named "foo" is not expected here.`,
+			Range:    &tfjson.Range{Filename: "main.tf", Start: tfjson.Pos{Line: 3}},
+		}},
+	}
+	expected := "Error: Unsupported argument\n\n  on main.tf line 3:\n\nAn argument named \"foo\" is not expected here.\n\n"
+	if out := terraform.FormatValidateOutput(invalid); out != expected {
+		t.Errorf("Expected output %q, got %q", expected, out)
+	}
+
+	if out := terraform.FormatValidateOutput(&tfjson.ValidateOutput{Valid: true}); out != "Success! The configuration is valid.\n" {
+		t.Errorf("Unexpected output of a valid configuration %q", out)
+	}
+}
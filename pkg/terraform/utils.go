@@ -1,29 +1,39 @@
 package terraform
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v56/github"
 	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/rs/zerolog/log"
 	"github.com/shurcooL/githubv4"
 	"github.com/terraform-linters/tflint-plugin-sdk/tflint"
+	"github.com/terraform-tools/terraform-checker/pkg/plugin"
 	"github.com/terraform-tools/terraform-checker/pkg/utils"
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	tfDirConfigName     = ".tf-checker"
-	tfDirEnabledDefault = true
+	tfDirConfigName              = ".tf-checker"
+	tfDirEnabledDefault          = true
+	tfDirLockfileRequiredDefault = true
 )
 
 type TfDir struct {
-	path    string
-	enabled bool
+	path              string
+	enabled           bool
+	lockfilePlatforms []string
+	lockfileRequired  bool
+	plugins           []plugin.Config
+	engine            Engine
+	terragrunt        bool
+	checkTimeout      time.Duration
 }
 
 func (t *TfDir) Path() string {
@@ -34,8 +44,39 @@ func (t *TfDir) IsEnabled() bool {
 	return t.enabled
 }
 
+func (t *TfDir) LockfilePlatforms() []string {
+	return t.lockfilePlatforms
+}
+
+func (t *TfDir) IsLockfileRequired() bool {
+	return t.lockfileRequired
+}
+
+func (t *TfDir) Plugins() []plugin.Config {
+	return t.plugins
+}
+
+func (t *TfDir) Engine() Engine {
+	return t.engine
+}
+
+// CheckTimeout returns the timeout of the checks of the TfDir, zero if it is not set.
+func (t *TfDir) CheckTimeout() time.Duration {
+	return t.checkTimeout
+}
+
+// IsTerragrunt returns true if the TfDir is a Terragrunt unit.
+func (t *TfDir) IsTerragrunt() bool {
+	return t.terragrunt
+}
+
 type TfDirConfigFile struct {
-	Enabled bool `yaml:"enabled"`
+	Enabled           bool            `yaml:"enabled"`
+	LockfilePlatforms []string        `yaml:"lockfile_platforms"`
+	LockfileRequired  bool            `yaml:"lockfile_required"`
+	Plugins           []plugin.Config `yaml:"plugins"`
+	Engine            string          `yaml:"engine"`
+	CheckTimeout      time.Duration   `yaml:"check_timeout"`
 }
 
 func parseTfDirConfig(path string) TfDirConfigFile {
@@ -43,7 +84,9 @@ func parseTfDirConfig(path string) TfDirConfigFile {
 	var err error
 
 	t := TfDirConfigFile{
-		Enabled: tfDirEnabledDefault,
+		Enabled:           tfDirEnabledDefault,
+		LockfilePlatforms: DefaultLockfilePlatforms(),
+		LockfileRequired:  tfDirLockfileRequiredDefault,
 	}
 
 	if data, err = os.ReadFile(path); err != nil {
@@ -62,21 +105,36 @@ func NewTfDir(path string) *TfDir {
 	}
 	conf := parseTfDirConfig(fmt.Sprintf("%s/%s", path, tfDirConfigName))
 	newTfDir.enabled = conf.Enabled
+	newTfDir.lockfilePlatforms = conf.LockfilePlatforms
+	newTfDir.lockfileRequired = conf.LockfileRequired
+	newTfDir.engine = FindEngine(path)
+	newTfDir.terragrunt = IsTerragruntUnit(path)
+	newTfDir.checkTimeout = conf.CheckTimeout
+	for _, p := range conf.Plugins {
+		if err := validateDirPlugin(p); err != nil {
+			log.Error().Err(err).Msgf("skipping plugin declared in %s", path)
+			continue
+		}
+		newTfDir.plugins = append(newTfDir.plugins, p)
+	}
 	return &newTfDir
 }
 
-// FindAllTfDir finds all of the terraform directory inside a directory.
+// FindAllTfDir finds all of the terraform directory inside a directory, Terragrunt units included.
 func FindAllTfDir(dir string) (tfDirs []*TfDir) {
 	tfDirsPaths := []string{}
 	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
 		if d.IsDir() {
-			if d.Name() == ".git" || d.Name() == ".terraform" {
+			if d.Name() == ".git" || d.Name() == ".terraform" || d.Name() == terragruntCacheDirName {
 				return filepath.SkipDir
 			}
 		}
 
 		currentPath := filepath.Dir(path)
-		if strings.HasSuffix(path, ".tf") && !utils.StrInSlice(tfDirsPaths, currentPath) {
+		if utils.StrInSlice(tfDirsPaths, currentPath) {
+			return nil
+		}
+		if strings.HasSuffix(path, ".tf") || (d.Name() == terragruntConfigName && IsTerragruntUnit(currentPath)) {
 			tfDirsPaths = append(tfDirsPaths, currentPath)
 		}
 		return nil
@@ -92,12 +150,66 @@ func FindAllTfDir(dir string) (tfDirs []*TfDir) {
 	return
 }
 
+// ChangedTfDirs returns the TfDirs affected by changedFiles, given relative to cloneDir.
+// A changed file affects the closest TfDir containing it, so that files read by a
+// terraform configuration from a sub folder (templates, scripts...) are taken into account.
+func ChangedTfDirs(tfDirs []*TfDir, cloneDir string, changedFiles []string) []*TfDir {
+	changed := []*TfDir{}
+	for _, file := range changedFiles {
+		fileDir := filepath.Dir(filepath.Join(cloneDir, file))
+
+		var closest *TfDir
+		for _, tfDir := range tfDirs {
+			if isSubDir(tfDir.Path(), fileDir) && (closest == nil || len(tfDir.Path()) > len(closest.Path())) {
+				closest = tfDir
+			}
+		}
+		if closest != nil && !containsTfDir(changed, closest) {
+			changed = append(changed, closest)
+		}
+	}
+	return changed
+}
+
+// isSubDir returns whether dir is parent or a sub folder of parent.
+func isSubDir(parent, dir string) bool {
+	rel, err := filepath.Rel(parent, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// repoDirs returns dir and its parents up to the root of its git repository, the closest one
+// containing .git. Only dir is returned outside of a git repository, so that the files of the
+// dirs containing the repository (home, clone dir of the server...) are never read.
+func repoDirs(dir string) []string {
+	dirs := []string{}
+	for current := dir; ; current = filepath.Dir(current) {
+		dirs = append(dirs, current)
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return dirs
+		}
+		if parent := filepath.Dir(current); parent == current {
+			return dirs[:1]
+		}
+	}
+}
+
+func containsTfDir(tfDirs []*TfDir, tfDir *TfDir) bool {
+	for _, t := range tfDirs {
+		if t.Path() == tfDir.Path() {
+			return true
+		}
+	}
+	return false
+}
+
 // InitTfLint goal is to launch tflint --init once at program startup.
-func InitTfLint() {
-	ok, out := tfLintInit()
+func InitTfLint(ctx context.Context) error {
+	ok, out := tfLintInit(ctx)
 	if !ok {
 		log.Error().Msgf("error while executing tflint --init. out: %s", out)
+		return fmt.Errorf("tflint --init failed: %s", strings.TrimSpace(out))
 	}
+	return nil
 }
 
 // TfLintRuleSeverityToAnnotationLevel allows to convert tflint severity to github annotation level.
@@ -135,3 +247,21 @@ func TfValidateSeverityToAnnotationLevel(severity tfjson.DiagnosticSeverity) *st
 
 	return github.String(strings.ToLower(string(finalStr)))
 }
+
+// PluginSeverityToAnnotationLevel allows to convert plugin annotation severity to github annotation level.
+func PluginSeverityToAnnotationLevel(severity string) *string {
+	var finalStr githubv4.CheckAnnotationLevel
+
+	switch strings.ToLower(severity) {
+	case "error", "failure":
+		finalStr = githubv4.CheckAnnotationLevelFailure
+	case "notice", "info":
+		finalStr = githubv4.CheckAnnotationLevelNotice
+	case "warning":
+		finalStr = githubv4.CheckAnnotationLevelWarning
+	default:
+		finalStr = githubv4.CheckAnnotationLevelWarning
+	}
+
+	return github.String(strings.ToLower(string(finalStr)))
+}
@@ -16,7 +16,52 @@ func TestFindAllTFDir(t *testing.T) {
 	}
 	dirs := terraform.FindAllTfDir(path)
 
-	if len(dirs) != 3 {
-		t.Errorf("Expected to find 3 tfdir, got %v, %v %v", len(dirs), dirs, path)
+	if len(dirs) != 5 {
+		t.Errorf("Expected to find 5 tfdir, got %v, %v %v", len(dirs), dirs, path)
+	}
+}
+
+func TestChangedTfDirs(t *testing.T) {
+	t.Parallel()
+
+	path, err := filepath.Abs("../../test")
+	if err != nil {
+		t.Errorf("Error getting directory %v", err)
+	}
+	dirs := terraform.FindAllTfDir(path)
+
+	testCases := []struct {
+		name         string
+		changedFiles []string
+		expected     []string
+	}{
+		{
+			name:         "tf files",
+			changedFiles: []string{"terraform_ok/main.tf", "terraform_invalid/main.tf", "terraform_ok/variables.tf"},
+			expected:     []string{"terraform_ok", "terraform_invalid"},
+		}, {
+			name:         "sub folder",
+			changedFiles: []string{"terraform_bad_fmt/templates/user_data.sh"},
+			expected:     []string{"terraform_bad_fmt"},
+		}, {
+			name:         "outside of tfDirs",
+			changedFiles: []string{"README.md", "terraform_oknot/main.tf"},
+			expected:     []string{},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			changed := terraform.ChangedTfDirs(dirs, path, tc.changedFiles)
+			if len(changed) != len(tc.expected) {
+				t.Fatalf("Expected %v changed tfDirs, got %v", tc.expected, changed)
+			}
+			for i, tfDir := range changed {
+				if tfDir.Path() != filepath.Join(path, tc.expected[i]) {
+					t.Errorf("Expected %v changed tfDirs, got %v", tc.expected, changed)
+				}
+			}
+		})
 	}
 }
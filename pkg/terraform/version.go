@@ -0,0 +1,152 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/terraform-tools/terraform-checker/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const tfVersionFileName = ".terraform-version"
+
+// checkVersionsDir checks that the versions dir, if any, is a directory.
+func checkVersionsDir(versionsDir string) error {
+	if versionsDir == "" {
+		return nil
+	}
+	if info, err := os.Stat(versionsDir); err != nil || !info.IsDir() {
+		return errors.TerraformVersionNotFoundError(fmt.Sprintf("terraform versions dir %s is not a directory", versionsDir))
+	}
+	return nil
+}
+
+// FindRequiredVersion returns the required_version constraints of the terraform files of dir,
+// empty if there are none.
+func FindRequiredVersion(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return "", err
+	}
+
+	parser := hclparse.NewParser()
+	constraints := []string{}
+	for _, f := range files {
+		file, diags := parser.ParseHCLFile(f)
+		if diags.HasErrors() {
+			return "", diags
+		}
+
+		content, _, _ := file.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{{Type: "terraform"}},
+		})
+		for _, tfBlock := range content.Blocks {
+			tfContent, _, _ := tfBlock.Body.PartialContent(&hcl.BodySchema{
+				Attributes: []hcl.AttributeSchema{{Name: "required_version"}},
+			})
+			attr, ok := tfContent.Attributes["required_version"]
+			if !ok {
+				continue
+			}
+			if val, diags := attr.Expr.Value(nil); !diags.HasErrors() && !val.IsNull() && val.Type() == cty.String {
+				constraints = append(constraints, val.AsString())
+			}
+		}
+	}
+	return strings.Join(constraints, ", "), nil
+}
+
+// findVersionFile returns the version of the closest version file of the engine in dir or its
+// parents up to the repository root, empty if there is none.
+func findVersionFile(engine Engine, dir string) string {
+	for _, current := range repoDirs(dir) {
+		if data, err := os.ReadFile(filepath.Join(current, engine.versionFileName())); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return ""
+}
+
+// InstalledTerraformVersions returns the versions of the engine installed in versionsDir,
+// from the newest one.
+func InstalledTerraformVersions(engine Engine, versionsDir string) ([]*version.Version, error) {
+	entries, err := os.ReadDir(versionsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []*version.Version{}
+	for _, entry := range entries {
+		v, err := version.NewVersion(entry.Name())
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(versionsDir, entry.Name(), engine.Binary())); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	sort.Sort(sort.Reverse(version.Collection(versions)))
+	return versions, nil
+}
+
+// FindTerraformBinary returns the binary of the engine to run in dir. Without versions dir, it is
+// taken from the PATH. Otherwise the version of the .terraform-version file is used if any, or
+// the newest installed version satisfying the required_version constraints of dir.
+func FindTerraformBinary(engine Engine, versionsDir, dir string) (string, error) {
+	if versionsDir == "" {
+		return engine.Binary(), nil
+	}
+
+	installed, err := InstalledTerraformVersions(engine, versionsDir)
+	if err != nil {
+		return "", err
+	}
+	binary := func(v *version.Version) string {
+		return filepath.Join(versionsDir, v.Original(), engine.Binary())
+	}
+
+	if versionFile := findVersionFile(engine, dir); versionFile != "" {
+		wanted, err := version.NewVersion(versionFile)
+		if err != nil {
+			return "", errors.TerraformVersionNotFoundError(fmt.Sprintf("invalid version %s in %s file", versionFile, engine.versionFileName()))
+		}
+		for _, v := range installed {
+			if v.Equal(wanted) {
+				return binary(v), nil
+			}
+		}
+		return "", errors.TerraformVersionNotFoundError(fmt.Sprintf("%s %s required by %s file is not installed, installed versions: %s", engine, versionFile, engine.versionFileName(), versionsString(installed)))
+	}
+
+	requiredVersion, err := FindRequiredVersion(dir)
+	if err != nil {
+		return "", err
+	}
+	constraints, err := version.NewConstraint(requiredVersion)
+	if requiredVersion != "" && err != nil {
+		return "", errors.TerraformVersionNotFoundError(fmt.Sprintf("invalid required_version %s", requiredVersion))
+	}
+	for _, v := range installed {
+		if requiredVersion == "" || constraints.Check(v) {
+			return binary(v), nil
+		}
+	}
+	return "", errors.TerraformVersionNotFoundError(fmt.Sprintf("no installed %s version satisfies required_version %s, installed versions: %s", engine, requiredVersion, versionsString(installed)))
+}
+
+func versionsString(versions []*version.Version) string {
+	if len(versions) == 0 {
+		return "none"
+	}
+	s := make([]string, 0, len(versions))
+	for _, v := range versions {
+		s = append(s, v.Original())
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,155 @@
+package terraform_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/terraform-tools/terraform-checker/pkg/terraform"
+)
+
+func newTestVersionsDir(t *testing.T, versions ...string) string {
+	t.Helper()
+	versionsDir := t.TempDir()
+	for _, v := range versions {
+		if err := os.MkdirAll(filepath.Join(versionsDir, v), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(versionsDir, v, "terraform"), []byte{}, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Not a terraform version, ignored
+	if err := os.MkdirAll(filepath.Join(versionsDir, "latest"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return versionsDir
+}
+
+func TestFindTerraformBinary(t *testing.T) {
+	t.Parallel()
+
+	versionsDir := newTestVersionsDir(t, "1.3.9", "1.5.7", "1.6.0")
+
+	testCases := []struct {
+		name           string
+		files          map[string]string
+		dir            string
+		versionsDir    string
+		expected       string
+		expectedErrors []string
+	}{
+		{
+			name:        "no versions dir",
+			files:       map[string]string{"main.tf": `terraform { required_version = "~> 1.3.0" }`},
+			versionsDir: "",
+			expected:    "terraform",
+		}, {
+			name:        "no required_version",
+			files:       map[string]string{"main.tf": `resource "null_resource" "a" {}`},
+			versionsDir: versionsDir,
+			expected:    filepath.Join(versionsDir, "1.6.0", "terraform"),
+		}, {
+			name: "required_version",
+			files: map[string]string{
+				"main.tf":     `resource "null_resource" "a" {}`,
+				"versions.tf": `terraform { required_version = ">= 1.3, < 1.6" }`,
+			},
+			versionsDir: versionsDir,
+			expected:    filepath.Join(versionsDir, "1.5.7", "terraform"),
+		}, {
+			name: "version file",
+			files: map[string]string{
+				"main.tf":            `terraform { required_version = ">= 1.3" }`,
+				".terraform-version": "1.3.9\n",
+			},
+			versionsDir: versionsDir,
+			expected:    filepath.Join(versionsDir, "1.3.9", "terraform"),
+		}, {
+			name: "version file of the repo root",
+			files: map[string]string{
+				".git/HEAD":          "",
+				".terraform-version": "1.3.9\n",
+				"stack/main.tf":      `resource "null_resource" "a" {}`,
+			},
+			dir:         "stack",
+			versionsDir: versionsDir,
+			expected:    filepath.Join(versionsDir, "1.3.9", "terraform"),
+		}, {
+			name: "version file outside of the repo",
+			files: map[string]string{
+				".terraform-version": "1.3.9\n",
+				"repo/.git/HEAD":     "",
+				"repo/main.tf":       `resource "null_resource" "a" {}`,
+			},
+			dir:         "repo",
+			versionsDir: versionsDir,
+			expected:    filepath.Join(versionsDir, "1.6.0", "terraform"),
+		}, {
+			name:           "no matching version",
+			files:          map[string]string{"main.tf": `terraform { required_version = "~> 1.4.0" }`},
+			versionsDir:    versionsDir,
+			expectedErrors: []string{"~> 1.4.0", "1.6.0, 1.5.7, 1.3.9"},
+		}, {
+			name:           "version file not installed",
+			files:          map[string]string{".terraform-version": "1.4.6"},
+			versionsDir:    versionsDir,
+			expectedErrors: []string{"terraform 1.4.6", "not installed"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			binary, err := terraform.FindTerraformBinary(terraform.EngineTerraform, tc.versionsDir, filepath.Join(dir, tc.dir))
+			if len(tc.expectedErrors) > 0 {
+				if err == nil {
+					t.Fatalf("Expected an error, got binary %s", binary)
+				}
+				for _, e := range tc.expectedErrors {
+					if !strings.Contains(err.Error(), e) {
+						t.Errorf("Expected error %q to contain %q", err, e)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if binary != tc.expected {
+				t.Errorf("Expected binary %s, got %s", tc.expected, binary)
+			}
+		})
+	}
+}
+
+func TestFindRequiredVersion(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.tf"), []byte(`terraform { required_version = ">= 1.3" }`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.tf"), []byte(`terraform { required_version = "< 2.0" }`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	requiredVersion, err := terraform.FindRequiredVersion(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requiredVersion != ">= 1.3, < 2.0" {
+		t.Errorf("Expected required version %q, got %q", ">= 1.3, < 2.0", requiredVersion)
+	}
+}
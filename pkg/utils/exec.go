@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// commandWaitDelay bounds the wait for the output of a killed command, in case its pipes are
+// held open by a process that left its group.
+const commandWaitDelay = 10 * time.Second
+
+// CommandContext returns a command run in its own process group. The whole group is killed when
+// ctx is done, so that the plugins or sub processes started by the command do not outlive it.
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = commandWaitDelay
+	return cmd
+}
@@ -0,0 +1,13 @@
+terraform {
+  required_version = "~> 1.0"
+
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "~> 3.0"
+    }
+    random = {
+      source = "hashicorp/random"
+    }
+  }
+}